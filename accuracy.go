@@ -0,0 +1,119 @@
+package qldemo
+
+// WeaponAccuracy counts the shots of a weapon and how many of them hit.
+type WeaponAccuracy struct {
+	Shots int
+	Hits  int
+}
+
+func (self *WeaponAccuracy) Accuracy() float64 {
+	if self.Shots == 0 {
+		return 0
+	}
+	return float64(self.Hits) / float64(self.Shots)
+}
+
+// Shot is a weapon fire event of the recording player.
+type Shot struct {
+	Time   int
+	Weapon Weapon
+	Hit    bool
+}
+
+/* -------------------------------------------- */
+// AccuracyTracker estimates the weapon accuracy of the recording player from
+// its EV_FIRE_WEAPON events and PERS_HITS. Hits counted in a snapshot are
+// credited to the latest shot, once however many pellets or splash targets
+// they were, so a rocket that lands after another weapon was fired is
+// credited to that weapon. Hits are credited at the following snapshot, so
+// callers of Events or Iterate must call Flush after the last event; Walk
+// does so through OnEnd. The player state carries no damage dealt, so only
+// hits are measured.
+type AccuracyTracker struct {
+	Weapons map[Weapon]*WeaponAccuracy
+	Shots   []Shot
+	client  int
+	weapon  Weapon
+	hits    int
+	pending int
+	valid   bool
+}
+
+func NewAccuracyTracker() *AccuracyTracker {
+	return &AccuracyTracker{Weapons: make(map[Weapon]*WeaponAccuracy), Shots: []Shot{}}
+}
+
+func (self *AccuracyTracker) Reset() {
+	*self = *NewAccuracyTracker()
+}
+
+func (self *AccuracyTracker) OnEvent(event Event) error {
+	switch typed := event.(type) {
+	case *Gamestate, *MapRestart:
+		self.Reset()
+	case *Snapshot:
+		self.onSnapshot(&typed.Player)
+	case *GameEvent:
+		if typed.Event == EV_FIRE_WEAPON && typed.State == nil && self.valid {
+			self.Shots = append(self.Shots, Shot{Time: typed.ServerTime, Weapon: self.weapon})
+			self.accuracy(self.weapon).Shots += 1
+		}
+	}
+	return nil
+}
+
+func (self *AccuracyTracker) accuracy(weapon Weapon) *WeaponAccuracy {
+	accuracy, ok := self.Weapons[weapon]
+	if !ok {
+		accuracy = &WeaponAccuracy{}
+		self.Weapons[weapon] = accuracy
+	}
+	return accuracy
+}
+
+// Flush credits the hits of the latest snapshot to the latest shot.
+func (self *AccuracyTracker) Flush() {
+	if self.pending > 0 && len(self.Shots) > 0 {
+		if shot := &self.Shots[len(self.Shots)-1]; !shot.Hit {
+			shot.Hit = true
+			self.accuracy(shot.Weapon).Hits += 1
+		}
+	}
+	self.pending = 0
+}
+
+func (self *AccuracyTracker) OnEnd() error {
+	self.Flush()
+	return nil
+}
+
+func (self *AccuracyTracker) onSnapshot(player *Player) {
+	self.Flush()
+	hits := player.Pers(PERS_HITS)
+	if self.valid && self.client == player.ClientNum {
+		self.pending = hits - self.hits
+	}
+	self.valid, self.client, self.hits = true, player.ClientNum, hits
+	self.weapon = player.CurrentWeapon()
+}
+
+// Window returns the accuracy of the shots fired from time from up to but
+// not including time to.
+func (self *AccuracyTracker) Window(from, to int) map[Weapon]*WeaponAccuracy {
+	result := map[Weapon]*WeaponAccuracy{}
+	for _, shot := range self.Shots {
+		if shot.Time < from || shot.Time >= to {
+			continue
+		}
+		accuracy, ok := result[shot.Weapon]
+		if !ok {
+			accuracy = &WeaponAccuracy{}
+			result[shot.Weapon] = accuracy
+		}
+		accuracy.Shots += 1
+		if shot.Hit {
+			accuracy.Hits += 1
+		}
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package qldemo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDuelAccuracy(tst *testing.T) {
+	tracker := NewAccuracyTracker()
+	replayDuel(tst, NewDemoState(), tracker)
+	if len(tracker.Shots) == 0 {
+		tst.Fatalf("Expected shots in the demo.")
+	}
+	rail := tracker.Weapons[WP_RAILGUN]
+	if rail == nil || rail.Shots == 0 || rail.Hits == 0 || rail.Accuracy() > 1 {
+		tst.Errorf("Unexpected railgun accuracy %+v.", rail)
+	}
+	if window := tracker.Window(0, tracker.Shots[len(tracker.Shots)-1].Time+1); !reflect.DeepEqual(window, tracker.Weapons) {
+		tst.Errorf("Expected the whole demo window to match the totals.")
+	}
+	if window := tracker.Window(0, tracker.Shots[0].Time); len(window) != 0 {
+		tst.Errorf("Expected no shots before the first one but got %v.", window)
+	}
+}
+
+func TestAccuracyFlush(tst *testing.T) {
+	tracker := NewAccuracyTracker()
+	snapshot := &Snapshot{Player: Player{Weapon: int(WP_RAILGUN)}}
+	tracker.OnEvent(snapshot)
+	tracker.OnEvent(&GameEvent{Event: EV_FIRE_WEAPON})
+	snapshot.Player.Persistant[PERS_HITS] = 1
+	tracker.OnEvent(snapshot)
+	tracker.Flush()
+	if rail := tracker.Weapons[WP_RAILGUN]; rail == nil || rail.Shots != 1 || rail.Hits != 1 {
+		tst.Errorf("Expected the hit of the last snapshot to be credited but got %+v.", rail)
+	}
+}
+
+func TestAccuracyWalk(tst *testing.T) {
+	walked, flushed := NewAccuracyTracker(), NewAccuracyTracker()
+	if err := NewDemoReader(bytes.NewReader(loadDuel(tst)), NewDemoState()).Walk(walked); err != nil {
+		tst.Fatalf("Unexpected error: %v", err)
+	}
+	replayDuel(tst, NewDemoState(), flushed)
+	if !reflect.DeepEqual(walked.Weapons, flushed.Weapons) {
+		tst.Errorf("Expected Walk to flush the tracker at the end of the demo.")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vincasmiliunas/quake-live-demo-reader"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] demo.dm_73\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	commands := flag.Bool("commands", false, "print server commands")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	demoState := qldemo.NewDemoState()
+	reader := qldemo.NewDemoReader(bufio.NewReader(file), demoState)
+
+	counts := make(map[qldemo.EventKind]int)
+	kinds := []qldemo.EventKind{}
+	for entry, err := range reader.Events() {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			file.Close()
+			os.Exit(1)
+		}
+		if counts[entry.Kind()] == 0 {
+			kinds = append(kinds, entry.Kind())
+		}
+		counts[entry.Kind()] += 1
+		if command, ok := entry.(*qldemo.Command); ok && *commands {
+			fmt.Printf("%d %q\n", command.Id, command.Str)
+		}
+	}
+
+	serverInfo := demoState.ServerInfo()
+	fmt.Printf("%s on %s, %s\n", serverInfo.GameType, serverInfo.MapName, serverInfo.Hostname.Clean())
+	for _, kind := range kinds {
+		fmt.Printf("%v: %d, ", kind, counts[kind])
+	}
+	fmt.Printf("entities: %d\n", len(demoState.Entities))
+}
@@ -0,0 +1,116 @@
+package qldemo
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+type Color int
+
+const (
+	COLOR_BLACK Color = iota
+	COLOR_RED
+	COLOR_GREEN
+	COLOR_YELLOW
+	COLOR_BLUE
+	COLOR_CYAN
+	COLOR_MAGENTA
+	COLOR_WHITE
+)
+
+var colorNames = map[Color]string{
+	COLOR_BLACK:   "black",
+	COLOR_RED:     "red",
+	COLOR_GREEN:   "green",
+	COLOR_YELLOW:  "yellow",
+	COLOR_BLUE:    "blue",
+	COLOR_CYAN:    "cyan",
+	COLOR_MAGENTA: "magenta",
+	COLOR_WHITE:   "white",
+}
+
+func (self Color) String() string {
+	if name, ok := colorNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("Color(%d)", int(self))
+}
+
+var colorANSI = [...]string{"30", "31", "32", "33", "34", "36", "35", "37"}
+var colorHTML = [...]string{"#000000", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#00ffff", "#ff00ff", "#ffffff"}
+
+/* -------------------------------------------- */
+// ColorString is text with Quake colour codes: a caret followed by any
+// character but another caret selects colour (character - '0') & 7.
+type ColorString string
+
+// ColorSpan is a run of text drawn in a single colour.
+type ColorSpan struct {
+	Color Color
+	Text  string
+}
+
+func isColorCode(str string, i int) bool {
+	return str[i] == '^' && i+1 < len(str) && str[i+1] != '^'
+}
+
+// Clean returns the text with the colour codes removed.
+func (self ColorString) Clean() string {
+	str := string(self)
+	result := make([]byte, 0, len(str))
+	for i := 0; i < len(str); i += 1 {
+		if isColorCode(str, i) {
+			i += 1
+			continue
+		}
+		result = append(result, str[i])
+	}
+	return string(result)
+}
+
+// Spans splits the text into coloured spans, starting in white. Empty spans
+// are dropped.
+func (self ColorString) Spans() []ColorSpan {
+	str := string(self)
+	result := []ColorSpan{}
+	color, text := COLOR_WHITE, []byte{}
+	for i := 0; i < len(str); i += 1 {
+		if isColorCode(str, i) {
+			if len(text) > 0 {
+				result = append(result, ColorSpan{color, string(text)})
+				text = text[:0]
+			}
+			color = Color((str[i+1] - '0') & 7)
+			i += 1
+			continue
+		}
+		text = append(text, str[i])
+	}
+	if len(text) > 0 {
+		result = append(result, ColorSpan{color, string(text)})
+	}
+	return result
+}
+
+// ANSI renders the text with ANSI terminal colour escapes, resetting the
+// terminal colour at the end.
+func (self ColorString) ANSI() string {
+	var builder strings.Builder
+	for _, span := range self.Spans() {
+		fmt.Fprintf(&builder, "\x1b[%sm%s", colorANSI[span.Color], span.Text)
+	}
+	if builder.Len() > 0 {
+		builder.WriteString("\x1b[0m")
+	}
+	return builder.String()
+}
+
+// HTML renders the text as escaped HTML with a <span> per coloured span.
+func (self ColorString) HTML() string {
+	var builder strings.Builder
+	for _, span := range self.Spans() {
+		fmt.Fprintf(&builder, `<span style="color:%s">%s</span>`, colorHTML[span.Color], html.EscapeString(span.Text))
+	}
+	return builder.String()
+}
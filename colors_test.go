@@ -0,0 +1,26 @@
+package qldemo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColorString(tst *testing.T) {
+	str := ColorString("^7sqL^1Tt ^^7Zen<Aku>^")
+	if str.Clean() != "sqLTt ^Zen<Aku>^" {
+		tst.Errorf("Unexpected clean string %q.", str.Clean())
+	}
+	expected := []ColorSpan{{COLOR_WHITE, "sqL"}, {COLOR_RED, "Tt ^"}, {COLOR_WHITE, "Zen<Aku>^"}}
+	if !reflect.DeepEqual(str.Spans(), expected) {
+		tst.Errorf("Unexpected spans %+v.", str.Spans())
+	}
+	if ansi := ColorString("a^1b").ANSI(); ansi != "\x1b[37ma\x1b[31mb\x1b[0m" {
+		tst.Errorf("Unexpected ANSI rendering %q.", ansi)
+	}
+	if html := ColorString("^2<gg>").HTML(); html != `<span style="color:#00ff00">&lt;gg&gt;</span>` {
+		tst.Errorf("Unexpected HTML rendering %q.", html)
+	}
+	if ColorString("^4").ANSI() != "" || len(ColorString("").Spans()) != 0 {
+		tst.Errorf("Expected no spans for a string without text.")
+	}
+}
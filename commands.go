@@ -0,0 +1,241 @@
+package qldemo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TokenizeCommand splits a server command into arguments the way the engine
+// does: on whitespace, with double quotes grouping an argument.
+func TokenizeCommand(str string) []string {
+	result := []string{}
+	for i := 0; i < len(str); {
+		if str[i] <= ' ' {
+			i += 1
+			continue
+		}
+		if str[i] == '"' {
+			end := strings.IndexByte(str[i+1:], '"')
+			if end < 0 {
+				result = append(result, str[i+1:])
+				break
+			}
+			result = append(result, str[i+1:i+1+end])
+			i += end + 2
+			continue
+		}
+		start := i
+		for i < len(str) && str[i] > ' ' && str[i] != '"' {
+			i += 1
+		}
+		result = append(result, str[start:i])
+	}
+	return result
+}
+
+/* -------------------------------------------- */
+type Print struct {
+	EventInfo
+	Text ColorString
+}
+
+func (self *Print) Kind() EventKind {
+	return KIND_PRINT
+}
+
+type CenterPrint struct {
+	EventInfo
+	Text ColorString
+}
+
+func (self *CenterPrint) Kind() EventKind {
+	return KIND_CENTER_PRINT
+}
+
+// Chat is a public chat message. Client is -1 if the message does not carry
+// the sender's client number and Team is the sender's team in the roster.
+type Chat struct {
+	EventInfo
+	Client int
+	Name   ColorString
+	Team   Team
+	Text   ColorString
+}
+
+func (self *Chat) Kind() EventKind {
+	return KIND_CHAT
+}
+
+// TeamChat is a team chat message, which may carry the sender's location.
+type TeamChat struct {
+	EventInfo
+	Client   int
+	Name     ColorString
+	Location ColorString
+	Team     Team
+	Text     ColorString
+}
+
+func (self *TeamChat) Kind() EventKind {
+	return KIND_TEAM_CHAT
+}
+
+type ConfigString struct {
+	EventInfo
+	Index int
+	Value string
+}
+
+func (self *ConfigString) Kind() EventKind {
+	return KIND_CONFIG_STRING
+}
+
+// BigConfigString is one part of a config string too long for a single
+// command: part 0 starts it, 1 continues it and 2 ends it.
+type BigConfigString struct {
+	EventInfo
+	Part  int
+	Index int
+	Value string
+}
+
+func (self *BigConfigString) Kind() EventKind {
+	return KIND_BIG_CONFIG_STRING
+}
+
+// Scores is any of the scoreboard commands (scores, dscores, scores_ffa...),
+// with Type naming the command and Values holding its arguments.
+type Scores struct {
+	EventInfo
+	Type   string
+	Values []float64
+}
+
+func (self *Scores) Kind() EventKind {
+	return KIND_SCORES
+}
+
+type MapRestart struct {
+	EventInfo
+}
+
+func (self *MapRestart) Kind() EventKind {
+	return KIND_MAP_RESTART
+}
+
+type Disconnect struct {
+	EventInfo
+	Reason ColorString
+}
+
+func (self *Disconnect) Kind() EventKind {
+	return KIND_DISCONNECT
+}
+
+// UnknownCommand is a server command without a typed event.
+type UnknownCommand struct {
+	EventInfo
+	Name string
+	Args []string
+}
+
+func (self *UnknownCommand) Kind() EventKind {
+	return KIND_UNKNOWN_COMMAND
+}
+
+/* -------------------------------------------- */
+// ParseCommand decodes a server command into one of the command events. The
+// returned event has no EventInfo and chat teams are not resolved.
+func ParseCommand(str string) Event {
+	args := TokenizeCommand(str)
+	if len(args) == 0 {
+		return &UnknownCommand{Args: []string{}}
+	}
+	name, args := args[0], args[1:]
+	arg := func(index int) string {
+		if index < len(args) {
+			return args[index]
+		}
+		return ""
+	}
+
+	switch {
+	case name == "print":
+		return &Print{Text: ColorString(arg(0))}
+	case name == "cp":
+		return &CenterPrint{Text: ColorString(arg(0))}
+	case name == "chat":
+		client, sender, text := splitChat(arg(0))
+		return &Chat{Client: client, Name: ColorString(sender), Text: ColorString(text)}
+	case name == "tchat":
+		client, sender, text := splitChat(arg(0))
+		sender, location := splitLocation(sender)
+		return &TeamChat{Client: client, Name: ColorString(sender), Location: ColorString(location), Text: ColorString(text)}
+	case name == "cs":
+		if index, err := strconv.Atoi(arg(0)); err == nil {
+			return &ConfigString{Index: index, Value: arg(1)}
+		}
+	case len(name) == 4 && strings.HasPrefix(name, "bcs"):
+		part, err1 := strconv.Atoi(name[3:])
+		index, err2 := strconv.Atoi(arg(0))
+		if err1 == nil && err2 == nil {
+			return &BigConfigString{Part: part, Index: index, Value: arg(1)}
+		}
+	case strings.HasPrefix(name, "scores") || name == "dscores":
+		values := make([]float64, len(args))
+		for i, value := range args {
+			values[i], _ = strconv.ParseFloat(value, 64)
+		}
+		return &Scores{Type: name, Values: values}
+	case name == "map_restart":
+		return &MapRestart{}
+	case name == "disconnect":
+		return &Disconnect{Reason: ColorString(arg(0))}
+	}
+	return &UnknownCommand{Name: name, Args: args}
+}
+
+// splitChat splits a chat message of the form "NN name\x19: text" into the
+// client number, sender name and text.
+func splitChat(message string) (int, string, string) {
+	client := -1
+	if len(message) > 2 && message[2] == ' ' {
+		if value, err := strconv.Atoi(message[:2]); err == nil {
+			client, message = value, message[3:]
+		}
+	}
+	if index := strings.Index(message, "\x19: "); index >= 0 {
+		return client, strings.ReplaceAll(message[:index], "\x19", ""), message[index+3:]
+	}
+	return client, "", message
+}
+
+// splitLocation splits a team chat sender of the form "(name) (location)".
+func splitLocation(sender string) (string, string) {
+	if !strings.HasPrefix(sender, "(") {
+		return sender, ""
+	}
+	end := strings.LastIndex(sender, ")")
+	if index := strings.Index(sender, ") ("); index >= 0 {
+		return sender[1:index], sender[index+3 : end]
+	} else if end > 0 {
+		return sender[1:end], ""
+	}
+	return sender, ""
+}
+
+// command parses a fresh server command and resolves the chat sender's team.
+func (self *DemoState) command(str string) Event {
+	entry := ParseCommand(str)
+	switch typed := entry.(type) {
+	case *Chat:
+		if player, ok := self.Roster[typed.Client]; ok {
+			typed.Team = player.Team
+		}
+	case *TeamChat:
+		if player, ok := self.Roster[typed.Client]; ok {
+			typed.Team = player.Team
+		}
+	}
+	return entry
+}
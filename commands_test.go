@@ -0,0 +1,70 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(tst *testing.T) {
+	cases := []struct {
+		str      string
+		expected Event
+	}{
+		{"print \"Timelimit hit.\n\"", &Print{Text: "Timelimit hit.\n"}},
+		{`cp "^1Fight!"`, &CenterPrint{Text: "^1Fight!"}},
+		{"chat \"03 ^7sqL^1Tt ZenAku^7\x19: ^2hf gg\"", &Chat{Client: 3, Name: "^7sqL^1Tt ZenAku^7", Text: "^2hf gg"}},
+		{"chat \"Foo^7\x19: hi\"", &Chat{Client: -1, Name: "Foo^7", Text: "hi"}},
+		{"tchat \"01 \x19(Foo^7\x19) (Red Armor)\x19: ^5go\"", &TeamChat{Client: 1, Name: "Foo^7", Location: "Red Armor", Text: "^5go"}},
+		{`cs 529 "n\Foo\t\0"`, &ConfigString{Index: 529, Value: `n\Foo\t\0`}},
+		{`bcs1 0 "\sv_hostname\foo"`, &BigConfigString{Part: 1, Index: 0, Value: `\sv_hostname\foo`}},
+		{"dscores 38 5 0.50", &Scores{Type: "dscores", Values: []float64{38, 5, 0.5}}},
+		{"map_restart\n", &MapRestart{}},
+		{`disconnect "Server shutdown"`, &Disconnect{Reason: "Server shutdown"}},
+		{"rcmd stoprecord;wait;\n", &UnknownCommand{Name: "rcmd", Args: []string{"stoprecord;wait;"}}},
+		{`cs foo "bar"`, &UnknownCommand{Name: "cs", Args: []string{"foo", "bar"}}},
+	}
+	for _, c := range cases {
+		if result := ParseCommand(c.str); !reflect.DeepEqual(result, c.expected) {
+			tst.Errorf("Parsing %q gave %+v instead of %+v.", c.str, result, c.expected)
+		}
+	}
+}
+
+func TestDuelCommands(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	chats, restarts, gg := 0, 0, false
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		switch entry.Kind() {
+		case KIND_CHAT:
+			chat := entry.(*Chat)
+			if chat.Client == 3 && chat.Team != TEAM_FREE {
+				tst.Errorf("Expected ZenAku to chat from the free team but got %v.", chat.Team)
+			}
+			if chat.Text == "^2gg" {
+				gg = true
+				if chat.Client != 0 {
+					tst.Errorf("Expected the gg message from client 0 but got %v.", chat.Client)
+				}
+			}
+			chats += 1
+		case KIND_MAP_RESTART:
+			restarts += 1
+		}
+	}
+	if chats != 17 || restarts != 1 {
+		tst.Errorf("Expected each command once but got %v chats and %v restarts.", chats, restarts)
+	}
+	if !gg {
+		tst.Errorf("Expected a gg chat message but did not find it.")
+	}
+}
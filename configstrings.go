@@ -0,0 +1,196 @@
+package qldemo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MAX_CONFIGSTRINGS = 1024
+	MAX_MODELS        = 256
+	MAX_SOUNDS        = 256
+	MAX_CLIENTS       = 64
+	MAX_LOCATIONS     = 64
+)
+
+// Config string indexes of protocol 73.
+const (
+	CS_SERVERINFO       = 0
+	CS_SYSTEMINFO       = 1
+	CS_MUSIC            = 2
+	CS_MESSAGE          = 3
+	CS_MOTD             = 4
+	CS_WARMUP           = 5
+	CS_SCORES1          = 6
+	CS_SCORES2          = 7
+	CS_VOTE_TIME        = 8
+	CS_VOTE_STRING      = 9
+	CS_VOTE_YES         = 10
+	CS_VOTE_NO          = 11
+	CS_GAME_VERSION     = 12
+	CS_LEVEL_START_TIME = 13
+	CS_INTERMISSION     = 14
+	CS_ITEMS            = 15
+	CS_MODELS           = 17
+	CS_SOUNDS           = CS_MODELS + MAX_MODELS
+	CS_PLAYERS          = CS_SOUNDS + MAX_SOUNDS
+	CS_LOCATIONS        = CS_PLAYERS + MAX_CLIENTS
+	CS_PARTICLES        = CS_LOCATIONS + MAX_LOCATIONS
+
+	CS_FLAGSTATUS         = 658
+	CS_FIRSTPLACE         = 659
+	CS_SECONDPLACE        = 660
+	CS_ROUND_STATUS       = 661
+	CS_ROUND_TIME         = 662
+	CS_RED_PLAYERS_LEFT   = 663
+	CS_BLUE_PLAYERS_LEFT  = 664
+	CS_SHADERSTATE        = 665
+	CS_NEXTMAP            = 666
+	CS_PRACTICE           = 667
+	CS_FREECAM            = 668
+	CS_PAUSE_START_TIME   = 669
+	CS_PAUSE_END_TIME     = 670
+	CS_TIMEOUTS_RED       = 671
+	CS_TIMEOUTS_BLUE      = 672
+	CS_MODEL_OVERRIDE     = 673
+	CS_PLAYER_CYLINDERS   = 674
+	CS_DEBUGFLAGS         = 675
+	CS_ENABLEBREATH       = 676
+	CS_DMGTHROUGHDEPTH    = 677
+	CS_PMOVEINFO          = 682
+	CS_WEAPONINFO         = 683
+	CS_PLAYERINFO         = 684
+	CS_SCORE1STPLAYER     = 685
+	CS_SCORE2NDPLAYER     = 686
+	CS_CLIENTNUM1STPLAYER = 687
+	CS_CLIENTNUM2NDPLAYER = 688
+)
+
+type GameType int
+
+const (
+	GT_FFA GameType = iota
+	GT_DUEL
+	GT_RACE
+	GT_TDM
+	GT_CA
+	GT_CTF
+	GT_1FCTF
+	GT_OBELISK
+	GT_HARVESTER
+	GT_FREEZE
+	GT_DOMINATION
+	GT_AD
+	GT_RR
+)
+
+var gameTypeNames = map[GameType]string{
+	GT_FFA:        "Free For All",
+	GT_DUEL:       "Duel",
+	GT_RACE:       "Race",
+	GT_TDM:        "Team Deathmatch",
+	GT_CA:         "Clan Arena",
+	GT_CTF:        "Capture The Flag",
+	GT_1FCTF:      "One Flag CTF",
+	GT_OBELISK:    "Overload",
+	GT_HARVESTER:  "Harvester",
+	GT_FREEZE:     "Freeze Tag",
+	GT_DOMINATION: "Domination",
+	GT_AD:         "Attack and Defend",
+	GT_RR:         "Red Rover",
+}
+
+func (self GameType) String() string {
+	if name, ok := gameTypeNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("GameType(%d)", int(self))
+}
+
+/* -------------------------------------------- */
+// Info is a backslash delimited key-value config string.
+type Info map[string]string
+
+func ParseInfo(str string) Info {
+	result := make(Info)
+	parts := strings.Split(strings.TrimPrefix(str, `\`), `\`)
+	for i := 0; i+1 < len(parts); i += 2 {
+		result[parts[i]] = parts[i+1]
+	}
+	return result
+}
+
+func (self Info) Int(key string) int {
+	value, _ := strconv.Atoi(self[key])
+	return value
+}
+
+func (self Info) Bool(key string) bool {
+	return self.Int(key) != 0
+}
+
+type ServerInfo struct {
+	Info
+	MapName        string
+	Hostname       ColorString
+	GameName       string
+	Version        string
+	GameState      string
+	GameType       GameType
+	Protocol       int
+	MaxClients     int
+	TimeLimit      int
+	FragLimit      int
+	CaptureLimit   int
+	RoundLimit     int
+	LevelStartTime int
+}
+
+func ParseServerInfo(str string) *ServerInfo {
+	info := ParseInfo(str)
+	return &ServerInfo{
+		Info:           info,
+		MapName:        info["mapname"],
+		Hostname:       ColorString(info["sv_hostname"]),
+		GameName:       info["gamename"],
+		Version:        info["version"],
+		GameState:      info["g_gameState"],
+		GameType:       GameType(info.Int("g_gametype")),
+		Protocol:       info.Int("protocol"),
+		MaxClients:     info.Int("sv_maxclients"),
+		TimeLimit:      info.Int("timelimit"),
+		FragLimit:      info.Int("fraglimit"),
+		CaptureLimit:   info.Int("capturelimit"),
+		RoundLimit:     info.Int("roundlimit"),
+		LevelStartTime: info.Int("g_levelStartTime"),
+	}
+}
+
+type SystemInfo struct {
+	Info
+	ServerId int
+	Pure     bool
+	Cheats   bool
+	PakNames []string
+}
+
+func ParseSystemInfo(str string) *SystemInfo {
+	info := ParseInfo(str)
+	return &SystemInfo{
+		Info:     info,
+		ServerId: info.Int("sv_serverid"),
+		Pure:     info.Bool("sv_pure"),
+		Cheats:   info.Bool("sv_cheats"),
+		PakNames: strings.Fields(info["sv_pakNames"]),
+	}
+}
+
+/* -------------------------------------------- */
+func (self *DemoState) ServerInfo() *ServerInfo {
+	return ParseServerInfo(self.Config[CS_SERVERINFO])
+}
+
+func (self *DemoState) SystemInfo() *SystemInfo {
+	return ParseSystemInfo(self.Config[CS_SYSTEMINFO])
+}
@@ -0,0 +1,70 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseInfo(tst *testing.T) {
+	info := ParseInfo(`\mapname\campgrounds\g_gametype\1\sv_hostname\`)
+	if len(info) != 3 || info["mapname"] != "campgrounds" || info.Int("g_gametype") != 1 || info["sv_hostname"] != "" {
+		tst.Errorf("Unexpected info %v.", info)
+	}
+}
+
+func TestDuelServerInfo(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		if entry.Kind() == KIND_GAMESTATE {
+			break
+		}
+	}
+	serverInfo := demoState.ServerInfo()
+	if serverInfo.MapName != "verticalvengeance" || serverInfo.GameType != GT_DUEL ||
+		serverInfo.Protocol != 73 || serverInfo.TimeLimit != 10 || serverInfo.Hostname != "robot Ventz" {
+		tst.Errorf("Unexpected server info %+v.", *serverInfo)
+	}
+	if !demoState.SystemInfo().Pure {
+		tst.Errorf("Expected a pure server.")
+	}
+}
+
+func TestDuelConfigStringChanges(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Fatalf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	scores := false
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		change, ok := entry.(*ConfigStringChanged)
+		if !ok {
+			continue
+		}
+		if demoState.Config[change.Index] != change.New {
+			tst.Errorf("Config string %v was not applied.", change.Index)
+		}
+		scores = scores || (change.Index == CS_SCORES1 && change.Old == "0" && change.New == "1")
+	}
+	if !scores {
+		tst.Errorf("Expected the first frag to change CS_SCORES1.")
+	}
+	if demoState.ServerInfo().MapName != "verticalvengeance" {
+		tst.Errorf("Expected serverinfo to survive config string updates.")
+	}
+}
@@ -1,262 +1,249 @@
-package main
+package qldemo
+
+import "fmt"
 
 /* -------------------------------------------- */
+type Vector struct {
+	X float32
+	Y float32
+	Z float32
+}
+
+// Entity is entityState_t as sent by protocol 73.
 type Entity struct {
-	Animations struct {
-		A int
-		B int
-	}
-	Angles struct {
-		A struct {
-			X float32
-			Y float32
-			Z float32
-		}
-		B struct {
-			X float32
-			Y float32
-			Z float32
-		}
-	}
-	Client   int
-	Entities struct {
-		A int
-		B int
-		C int
-	}
-	Entity struct {
-		A int
-		B int
-	}
-	Events struct {
-		A int
-		B int
-	}
-	Misc struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-	}
-	Model struct {
-		A int
-		B int
-	}
-	Origins struct {
-		A struct {
-			X float32
-			Y float32
-			Z float32
-		}
-		B struct {
-			X float32
-			Y float32
-			Z float32
-		}
-	}
-	Powerups int
-	Time     struct {
-		A int
-		B int
-	}
-	Trajectories struct {
-		A Trajectory
-		B Trajectory
-	}
-	Weapon int
+	EType           int // classified by Type
+	EFlags          int
+	Pos             Trajectory
+	APos            Trajectory
+	Time            int
+	Time2           int
+	Origin          Vector
+	Origin2         Vector
+	Angles          Vector
+	Angles2         Vector
+	OtherEntityNum  int
+	OtherEntityNum2 int
+	GroundEntityNum int
+	ConstantLight   int
+	LoopSound       int
+	ModelIndex      int
+	ModelIndex2     int
+	ClientNum       int
+	Frame           int
+	Solid           int // packed bounding box of clipped entities
+	Event           int // event number and toggle bits
+	EventParm       int
+	Powerups        int // bit mask indexed by powerup
+	Weapon          int
+	LegsAnim        int
+	TorsoAnim       int
+	Generic1        int
 }
 
+// Trajectory is trajectory_t; Gravity is a protocol 73 addition.
 type Trajectory struct {
-	Base struct {
-		X float32
-		Y float32
-		Z float32
-	}
-	Delta struct {
-		X float32
-		Y float32
-		Z float32
-	}
+	Type     int
+	Time     int
 	Duration int
+	Base     Vector
+	Delta    Vector
 	Gravity  int
-	Mode     int
-	Time     int
 }
 
 /* -------------------------------------------- */
+// Player is playerState_t of the recording client as sent by protocol 73.
+// Stats, Persistant, Ammo and Powerups are indexed by the STAT_*, PERS_*,
+// WP_* and PW_* constants.
 type Player struct {
-	Ammunition struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-		F int
-		G int
-		H int
-		I int
-		J int
-		K int
-		L int
-		M int
-		N int
-		O int
-		P int
-	}
-	Animations struct {
-		A struct {
-			A int
-			B int
-		}
-		B struct {
-			A int
-			B int
-		}
-	}
-	Attributes struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-		F int
-		G int
-		H int
-		I int
-		J int
-		K int
-		L int
-		M int
-		N int
-		O int
-		P int
-	}
-	Client int
-	Damage struct {
-		A int
-		B int
-		C int
-		D int
-	}
-	Delta struct {
-		A int
-		B int
-		C int
-	}
-	Entities struct {
-		A int
-		B int
-	}
-	Entity struct {
-		A int
-	}
-	Event struct {
-		A int
-		B int
-		C int
-	}
-	Events struct {
-		A int
-		B int
-	}
-	External struct {
-		A int
-		B int
-	}
-	Grapple struct {
-		X float32
-		Y float32
-		Z float32
-	}
-	Origin struct {
-		X float32
-		Y float32
-		Z float32
-	}
-	Velocity struct {
-		X float32
-		Y float32
-		Z float32
-	}
-	View struct {
-		X float32
-		Y float32
-		Z float32
-	}
-	Misc struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-		F int
-	}
-	Movement struct {
-		A int
-		B int
-		C int
-		D int
-	}
-	Powerups struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-		F int
-		G int
-		H int
-		I int
-		J int
-		K int
-		L int
-		M int
-		N int
-		O int
-		P int
-	}
-	Time   int
-	Vitals struct {
-		A int
-		B int
-		C int
-		D int
-		E int
-		F int
-		G int
-		H int
-		I int
-		J int
-		K int
-		L int
-		M int
-		N int
-		O int
-		P int
-	}
-	Weapon struct {
-		A int
-		B int
-		C int
+	CommandTime       int
+	PMType            PMType
+	BobCycle          int
+	PMFlags           int
+	PMTime            int
+	Origin            Vector
+	Velocity          Vector
+	WeaponTime        int
+	Gravity           int
+	Speed             int
+	DeltaAngles       [3]int
+	GroundEntityNum   int
+	LegsTimer         int
+	LegsAnim          int
+	TorsoTimer        int
+	TorsoAnim         int
+	MovementDir       int
+	GrapplePoint      Vector
+	EFlags            int
+	EventSequence     int
+	Events            [MAX_PS_EVENTS]int
+	EventParms        [MAX_PS_EVENTS]int
+	ExternalEvent     int
+	ExternalEventParm int
+	ClientNum         int
+	Weapon            int
+	WeaponState       WeaponState
+	ViewAngles        Vector
+	ViewHeight        int
+	DamageEvent       int
+	DamageYaw         int
+	DamagePitch       int
+	DamageCount       int
+	Stats             [MAX_STATS]int
+	Persistant        [MAX_PERSISTANT]int
+	Ammo              [MAX_WEAPONS]int
+	Powerups          [MAX_POWERUPS]int // expiry times
+	Generic1          int
+	LoopSound         int
+	JumppadEnt        int
+}
+
+/* -------------------------------------------- */
+type EventKind int
+
+const (
+	KIND_NONE EventKind = iota
+	KIND_GAMESTATE
+	KIND_COMMAND
+	KIND_SNAPSHOT
+	KIND_SNAPSHOT_DROPPED
+	KIND_CONFIG_STRING_CHANGED
+	KIND_PLAYER_CONNECTED
+	KIND_PLAYER_DISCONNECTED
+	KIND_PLAYER_TEAM_CHANGED
+	KIND_PRINT
+	KIND_CENTER_PRINT
+	KIND_CHAT
+	KIND_TEAM_CHAT
+	KIND_CONFIG_STRING
+	KIND_BIG_CONFIG_STRING
+	KIND_SCORES
+	KIND_MAP_RESTART
+	KIND_DISCONNECT
+	KIND_UNKNOWN_COMMAND
+	KIND_GAME_EVENT
+	KIND_OBITUARY
+	KIND_ITEM_PICKUP
+)
+
+var eventKindNames = map[EventKind]string{
+	KIND_NONE:                  "none",
+	KIND_GAMESTATE:             "gamestate",
+	KIND_COMMAND:               "command",
+	KIND_SNAPSHOT:              "snapshot",
+	KIND_SNAPSHOT_DROPPED:      "snapshot dropped",
+	KIND_CONFIG_STRING_CHANGED: "config string changed",
+	KIND_PLAYER_CONNECTED:      "player connected",
+	KIND_PLAYER_DISCONNECTED:   "player disconnected",
+	KIND_PLAYER_TEAM_CHANGED:   "player team changed",
+	KIND_PRINT:                 "print",
+	KIND_CENTER_PRINT:          "center print",
+	KIND_CHAT:                  "chat",
+	KIND_TEAM_CHAT:             "team chat",
+	KIND_CONFIG_STRING:         "config string",
+	KIND_BIG_CONFIG_STRING:     "big config string",
+	KIND_SCORES:                "scores",
+	KIND_MAP_RESTART:           "map restart",
+	KIND_DISCONNECT:            "disconnect",
+	KIND_UNKNOWN_COMMAND:       "unknown command",
+	KIND_GAME_EVENT:            "game event",
+	KIND_OBITUARY:              "obituary",
+	KIND_ITEM_PICKUP:           "item pickup",
+}
+
+func (self EventKind) String() string {
+	if name, ok := eventKindNames[self]; ok {
+		return name
 	}
+	return fmt.Sprintf("EventKind(%d)", int(self))
+}
+
+// Event is implemented only by the event types of this package, which embed
+// EventInfo; consumers should route on Kind and keep a default case.
+type Event interface {
+	Kind() EventKind
+	Info() *EventInfo
+	event()
+}
+
+// EventInfo describes where in the demo an event was read from. BlockId is
+// the server message sequence from the block header, MessageId the first
+// value of the message itself, ServerTime the time of the latest snapshot
+// and Offset the byte offset of the block in the demo.
+type EventInfo struct {
+	BlockId    int
+	MessageId  int
+	ServerTime int
+	Offset     int
+}
+
+func (self *EventInfo) Info() *EventInfo {
+	return self
 }
 
+func (self *EventInfo) event() {}
+
 /* -------------------------------------------- */
 type Gamestate struct {
+	EventInfo
 	Id       int
 	Client   int
 	Checksum int
 }
 
+func (self *Gamestate) Kind() EventKind {
+	return KIND_GAMESTATE
+}
+
+// Snapshot carries the world state at server time Time. Player is a copy and
+// Entities is never modified after the snapshot is emitted.
 type Snapshot struct {
-	Time  int
-	Delta int
-	Flags int
-	Blob  []byte
+	EventInfo
+	Time     int
+	Delta    int
+	Flags    int
+	Blob     []byte
+	Player   Player
+	Entities map[int]*Entity
+}
+
+func (self *Snapshot) Kind() EventKind {
+	return KIND_SNAPSHOT
+}
+
+// SnapshotDropped replaces a Snapshot that could not be decoded because the
+// frame it is delta compressed against was not received or is too old.
+type SnapshotDropped struct {
+	EventInfo
+	Time          int
+	DeltaSequence int
+	Err           error
 }
 
+func (self *SnapshotDropped) Kind() EventKind {
+	return KIND_SNAPSHOT_DROPPED
+}
+
+// Command is emitted for every copy of a reliable server command; the first
+// copy is followed by the event ParseCommand decodes it into.
 type Command struct {
+	EventInfo
 	Id  int
 	Str string
 }
+
+func (self *Command) Kind() EventKind {
+	return KIND_COMMAND
+}
+
+// ConfigStringChanged is emitted after a server command has set config
+// string Index, even if to the value it already had.
+type ConfigStringChanged struct {
+	EventInfo
+	Index int
+	Old   string
+	New   string
+}
+
+func (self *ConfigStringChanged) Kind() EventKind {
+	return KIND_CONFIG_STRING_CHANGED
+}
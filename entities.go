@@ -0,0 +1,124 @@
+package qldemo
+
+import "fmt"
+
+type EntityType int
+
+// Entities with an eType above ET_EVENTS are temporary event entities carrying
+// event eType - ET_EVENTS, as cgame treats them.
+const (
+	ET_GENERAL EntityType = iota
+	ET_PLAYER
+	ET_ITEM
+	ET_MISSILE
+	ET_MOVER
+	ET_BEAM
+	ET_PORTAL
+	ET_SPEAKER
+	ET_PUSH_TRIGGER
+	ET_TELEPORT_TRIGGER
+	ET_INVISIBLE
+	ET_GRAPPLE
+	ET_TEAM
+	ET_EVENTS
+)
+
+var entityTypeNames = map[EntityType]string{
+	ET_GENERAL:          "general",
+	ET_PLAYER:           "player",
+	ET_ITEM:             "item",
+	ET_MISSILE:          "missile",
+	ET_MOVER:            "mover",
+	ET_BEAM:             "beam",
+	ET_PORTAL:           "portal",
+	ET_SPEAKER:          "speaker",
+	ET_PUSH_TRIGGER:     "push trigger",
+	ET_TELEPORT_TRIGGER: "teleport trigger",
+	ET_INVISIBLE:        "invisible",
+	ET_GRAPPLE:          "grapple",
+	ET_TEAM:             "team",
+	ET_EVENTS:           "events",
+}
+
+func (self EntityType) String() string {
+	if name, ok := entityTypeNames[self]; ok {
+		return name
+	} else if self > ET_EVENTS {
+		return fmt.Sprintf("events+%d", int(self-ET_EVENTS))
+	}
+	return fmt.Sprintf("EntityType(%d)", int(self))
+}
+
+// Type classifies the entity, folding all event entities into ET_EVENTS.
+func (self *Entity) Type() EntityType {
+	if self.IsEvent() {
+		return ET_EVENTS
+	}
+	return EntityType(self.EType)
+}
+
+func (self *Entity) IsEvent() bool {
+	return self.EType > int(ET_EVENTS)
+}
+
+/* -------------------------------------------- */
+func filterEntities(entities map[int]*Entity, match func(*Entity) bool) map[int]*Entity {
+	result := map[int]*Entity{}
+	for id, entity := range entities {
+		if match(entity) {
+			result[id] = entity
+		}
+	}
+	return result
+}
+
+// OfType returns the entities of the snapshot classified as entityType, keyed
+// by entity number.
+func (self *Snapshot) OfType(entityType EntityType) map[int]*Entity {
+	return filterEntities(self.Entities, func(entity *Entity) bool { return entity.Type() == entityType })
+}
+
+// Players returns the other players in the snapshot; the recording client is
+// described by Player instead. Corpses are player entities numbered from
+// MAX_CLIENTS up.
+func (self *Snapshot) Players() map[int]*Entity {
+	return self.OfType(ET_PLAYER)
+}
+
+func (self *Snapshot) Items() map[int]*Entity {
+	return self.OfType(ET_ITEM)
+}
+
+func (self *Snapshot) Missiles() map[int]*Entity {
+	return self.OfType(ET_MISSILE)
+}
+
+// EventEntities returns the temporary event entities of the snapshot, which
+// exclude any with an eType of exactly ET_EVENTS.
+func (self *Snapshot) EventEntities() map[int]*Entity {
+	return filterEntities(self.Entities, (*Entity).IsEvent)
+}
+
+// OfType returns the entities of the latest snapshot classified as
+// entityType, keyed by entity number.
+func (self *DemoState) OfType(entityType EntityType) map[int]*Entity {
+	return filterEntities(self.Entities, func(entity *Entity) bool { return entity.Type() == entityType })
+}
+
+// Players returns the other players in the latest snapshot, as
+// Snapshot.Players does.
+func (self *DemoState) Players() map[int]*Entity {
+	return self.OfType(ET_PLAYER)
+}
+
+func (self *DemoState) Items() map[int]*Entity {
+	return self.OfType(ET_ITEM)
+}
+
+func (self *DemoState) Missiles() map[int]*Entity {
+	return self.OfType(ET_MISSILE)
+}
+
+func (self *DemoState) EventEntities() map[int]*Entity {
+	return filterEntities(self.Entities, (*Entity).IsEvent)
+}
@@ -0,0 +1,57 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEntityTypes(tst *testing.T) {
+	if ET_EVENTS.String() != "events" || EntityType(ET_EVENTS+58).String() != "events+58" {
+		tst.Errorf("Unexpected entity type names.")
+	}
+	entity := &Entity{EType: int(ET_EVENTS) + 58}
+	if entity.Type() != ET_EVENTS || !entity.IsEvent() {
+		tst.Errorf("Expected an event entity but got %v.", entity.Type())
+	}
+	snapshot := &Snapshot{Entities: map[int]*Entity{1: {EType: int(ET_EVENTS)}, 2: entity}}
+	if events := snapshot.EventEntities(); len(events) != 1 || events[2] != entity {
+		tst.Errorf("Expected only entities above ET_EVENTS to be events but got %v.", events)
+	}
+}
+
+func TestDuelEntityTypes(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	rockets, events := 0, 0
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		snapshot, ok := entry.(*Snapshot)
+		if !ok {
+			continue
+		}
+		for id, player := range snapshot.Players() {
+			if id < MAX_CLIENTS && player.ClientNum != 0 {
+				tst.Fatalf("Expected only Ventz in the snapshot but got client %v.", player.ClientNum)
+			}
+		}
+		if len(snapshot.Items()) == 0 {
+			tst.Fatalf("Expected items in every snapshot.")
+		}
+		for _, missile := range snapshot.Missiles() {
+			if missile.Weapon == int(WP_ROCKET_LAUNCHER) {
+				rockets += 1
+			}
+		}
+		events += len(snapshot.EventEntities())
+	}
+	if rockets == 0 || events == 0 {
+		tst.Errorf("Expected rockets and event entities but got %v and %v.", rockets, events)
+	}
+}
@@ -0,0 +1,56 @@
+package qldemo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrTruncated = errors.New("qldemo: demo is truncated")
+
+var ErrStop = errors.New("qldemo: stop")
+
+var ErrStopped = errors.New("qldemo: reading was stopped")
+
+var ErrDeltaInvalid = errors.New("qldemo: delta from invalid frame")
+
+var ErrDeltaTooOld = errors.New("qldemo: delta frame too old")
+
+type ErrUnknownOpcode struct {
+	Loop        string
+	Code        int
+	BlockOffset int
+	BitOffset   int
+}
+
+func (self *ErrUnknownOpcode) Error() string {
+	return fmt.Sprintf("qldemo: invalid %s loop code %v in block at offset %v, bit %v",
+		self.Loop, self.Code, self.BlockOffset, self.BitOffset)
+}
+
+type ErrBadBigConfigString struct {
+	Index int
+	Part  int
+}
+
+func (self *ErrBadBigConfigString) Error() string {
+	return fmt.Sprintf("qldemo: big config string %v has invalid part %v", self.Index, self.Part)
+}
+
+type ErrBadFieldCount struct {
+	State string
+	Count int
+	Max   int
+}
+
+func (self *ErrBadFieldCount) Error() string {
+	return fmt.Sprintf("qldemo: %s state has %v fields but at most %v are known", self.State, self.Count, self.Max)
+}
+
+type ErrBadBlockLength struct {
+	Offset int
+	Length int
+}
+
+func (self *ErrBadBlockLength) Error() string {
+	return fmt.Sprintf("qldemo: invalid block length %v at offset %v", self.Length, self.Offset)
+}
@@ -0,0 +1,242 @@
+package qldemo
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	EV_EVENT_BITS    = 0x300 // toggled so that repeated events can be told apart
+	EF_PLAYER_EVENT  = 0x10  // event entity carrying a predictable player event
+	EVENT_VALID_MSEC = 300
+)
+
+type EntityEvent int
+
+const (
+	EV_NONE EntityEvent = iota
+	EV_FOOTSTEP
+	EV_FOOTSTEP_METAL
+	EV_FOOTSPLASH
+	EV_FOOTWADE
+	EV_SWIM
+	EV_FALL_SHORT
+	EV_FALL_MEDIUM
+	EV_FALL_FAR
+	EV_JUMP_PAD
+	EV_JUMP
+	EV_WATER_TOUCH
+	EV_WATER_LEAVE
+	EV_WATER_UNDER
+	EV_WATER_CLEAR
+	EV_ITEM_PICKUP
+	EV_GLOBAL_ITEM_PICKUP
+	EV_NOAMMO
+	EV_CHANGE_WEAPON
+	EV_DROP_WEAPON
+	EV_FIRE_WEAPON
+	EV_USE_ITEM0
+	EV_USE_ITEM1
+	EV_USE_ITEM2
+	EV_USE_ITEM3
+	EV_USE_ITEM4
+	EV_USE_ITEM5
+	EV_USE_ITEM6
+	EV_USE_ITEM7
+	EV_USE_ITEM8
+	EV_USE_ITEM9
+	EV_USE_ITEM10
+	EV_USE_ITEM11
+	EV_USE_ITEM12
+	EV_USE_ITEM13
+	EV_USE_ITEM14
+	EV_USE_ITEM15
+	EV_ITEM_RESPAWN
+	EV_ITEM_POP
+	EV_PLAYER_TELEPORT_IN
+	EV_PLAYER_TELEPORT_OUT
+	EV_GRENADE_BOUNCE
+	EV_GENERAL_SOUND
+	EV_GLOBAL_SOUND
+	EV_GLOBAL_TEAM_SOUND
+	EV_BULLET_HIT_FLESH
+	EV_BULLET_HIT_WALL
+	EV_MISSILE_HIT
+	EV_MISSILE_MISS
+	EV_MISSILE_MISS_METAL
+	EV_RAILTRAIL
+	EV_SHOTGUN
+	EV_BULLET
+	EV_PAIN
+	EV_DEATH1
+	EV_DEATH2
+	EV_DEATH3
+	EV_DROWN
+	EV_OBITUARY
+	EV_POWERUP_QUAD
+	EV_POWERUP_BATTLESUIT
+	EV_POWERUP_REGEN
+	EV_POWERUP_ARMOR_REGEN
+	EV_GIB_PLAYER
+	EV_SCOREPLUM
+)
+
+var entityEventNames = map[EntityEvent]string{
+	EV_NONE:                "none",
+	EV_FOOTSTEP:            "footstep",
+	EV_FOOTSTEP_METAL:      "footstep metal",
+	EV_FOOTSPLASH:          "footsplash",
+	EV_FOOTWADE:            "footwade",
+	EV_SWIM:                "swim",
+	EV_FALL_SHORT:          "fall short",
+	EV_FALL_MEDIUM:         "fall medium",
+	EV_FALL_FAR:            "fall far",
+	EV_JUMP_PAD:            "jump pad",
+	EV_JUMP:                "jump",
+	EV_WATER_TOUCH:         "water touch",
+	EV_WATER_LEAVE:         "water leave",
+	EV_WATER_UNDER:         "water under",
+	EV_WATER_CLEAR:         "water clear",
+	EV_ITEM_PICKUP:         "item pickup",
+	EV_GLOBAL_ITEM_PICKUP:  "global item pickup",
+	EV_NOAMMO:              "no ammo",
+	EV_CHANGE_WEAPON:       "change weapon",
+	EV_DROP_WEAPON:         "drop weapon",
+	EV_FIRE_WEAPON:         "fire weapon",
+	EV_ITEM_RESPAWN:        "item respawn",
+	EV_ITEM_POP:            "item pop",
+	EV_PLAYER_TELEPORT_IN:  "player teleport in",
+	EV_PLAYER_TELEPORT_OUT: "player teleport out",
+	EV_GRENADE_BOUNCE:      "grenade bounce",
+	EV_GENERAL_SOUND:       "general sound",
+	EV_GLOBAL_SOUND:        "global sound",
+	EV_GLOBAL_TEAM_SOUND:   "global team sound",
+	EV_BULLET_HIT_FLESH:    "bullet hit flesh",
+	EV_BULLET_HIT_WALL:     "bullet hit wall",
+	EV_MISSILE_HIT:         "missile hit",
+	EV_MISSILE_MISS:        "missile miss",
+	EV_MISSILE_MISS_METAL:  "missile miss metal",
+	EV_RAILTRAIL:           "railtrail",
+	EV_SHOTGUN:             "shotgun",
+	EV_BULLET:              "bullet",
+	EV_PAIN:                "pain",
+	EV_DEATH1:              "death1",
+	EV_DEATH2:              "death2",
+	EV_DEATH3:              "death3",
+	EV_DROWN:               "drown",
+	EV_OBITUARY:            "obituary",
+	EV_POWERUP_QUAD:        "powerup quad",
+	EV_POWERUP_BATTLESUIT:  "powerup battlesuit",
+	EV_POWERUP_REGEN:       "powerup regen",
+	EV_POWERUP_ARMOR_REGEN: "powerup armor regen",
+	EV_GIB_PLAYER:          "gib player",
+	EV_SCOREPLUM:           "scoreplum",
+}
+
+func (self EntityEvent) String() string {
+	if name, ok := entityEventNames[self]; ok {
+		return name
+	} else if self >= EV_USE_ITEM0 && self <= EV_USE_ITEM15 {
+		return fmt.Sprintf("use item%d", int(self-EV_USE_ITEM0))
+	}
+	return fmt.Sprintf("EntityEvent(%d)", int(self))
+}
+
+/* -------------------------------------------- */
+// GameEvent is an entity event fired in a snapshot, whether carried by a
+// temporary event entity, riding on another entity or predicted in the
+// recording client's player state. Client is the client the event belongs to
+// or -1, and State is nil for player state events.
+type GameEvent struct {
+	EventInfo
+	Event  EntityEvent
+	Parm   int
+	Entity int
+	Client int
+	Origin Vector
+	State  *Entity
+}
+
+func (self *GameEvent) Kind() EventKind {
+	return KIND_GAME_EVENT
+}
+
+// eventState remembers what the previous snapshots fired, the way the client
+// game does, so that each event is decoded exactly once.
+type eventState struct {
+	valid    bool
+	player   Player
+	previous map[int]int
+	seen     map[int]int
+}
+
+// gameEvents returns the events fired by snapshot, ordered by entity number
+// after those of the player state.
+func (self *DemoState) gameEvents(snapshot *Snapshot) []*GameEvent {
+	state := &self.events
+	if state.previous == nil {
+		state.previous, state.seen = make(map[int]int), make(map[int]int)
+	}
+	result := []*GameEvent{}
+
+	player := &snapshot.Player
+	fire := func(event, parm int) {
+		if event &^= EV_EVENT_BITS; event != 0 {
+			result = append(result, &GameEvent{Event: EntityEvent(event), Parm: parm,
+				Entity: player.ClientNum, Client: player.ClientNum, Origin: player.Origin})
+		}
+	}
+	if old := &state.player; state.valid && old.ClientNum == player.ClientNum {
+		if player.ExternalEvent != 0 && player.ExternalEvent != old.ExternalEvent {
+			fire(player.ExternalEvent, player.ExternalEventParm)
+		}
+		for i := player.EventSequence - MAX_PS_EVENTS; i < player.EventSequence; i += 1 {
+			slot := i & (MAX_PS_EVENTS - 1)
+			if i >= old.EventSequence ||
+				(i > old.EventSequence-MAX_PS_EVENTS && player.Events[slot] != old.Events[slot]) {
+				fire(player.Events[slot], player.EventParms[slot])
+			}
+		}
+	}
+	state.valid, state.player = true, *player
+
+	ids := make([]int, 0, len(snapshot.Entities))
+	for id := range snapshot.Entities {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		entity := snapshot.Entities[id]
+		if seen, ok := state.seen[id]; !ok || seen < snapshot.Time-EVENT_VALID_MSEC {
+			state.previous[id] = 0
+		}
+		state.seen[id] = snapshot.Time
+
+		var event int
+		if entity.IsEvent() {
+			if state.previous[id] != 0 {
+				continue
+			}
+			state.previous[id] = 1
+			event = entity.EType - int(ET_EVENTS)
+		} else {
+			if entity.Event == state.previous[id] {
+				continue
+			}
+			state.previous[id] = entity.Event
+			if event = entity.Event &^ EV_EVENT_BITS; event == 0 {
+				continue
+			}
+		}
+
+		client := -1
+		if entity.IsEvent() && entity.EFlags&EF_PLAYER_EVENT != 0 {
+			client = entity.OtherEntityNum
+		} else if entity.Type() == ET_PLAYER && id < MAX_CLIENTS {
+			client = id
+		}
+		result = append(result, &GameEvent{Event: EntityEvent(event), Parm: entity.EventParm,
+			Entity: id, Client: client, Origin: entity.Pos.Base, State: entity})
+	}
+	return result
+}
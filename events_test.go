@@ -0,0 +1,49 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"testing"
+)
+
+func TestGameEvents(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	counts := map[EntityEvent]int{}
+	time := 0
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		switch entry.Kind() {
+		case KIND_SNAPSHOT:
+			time = entry.(*Snapshot).Time
+		case KIND_GAME_EVENT:
+			event := entry.(*GameEvent)
+			if event.Info().ServerTime != time {
+				tst.Errorf("Expected %v to follow its snapshot.", event.Event)
+			}
+			if event.Event == EV_NONE {
+				tst.Errorf("Unexpected empty event from entity %v.", event.Entity)
+			}
+			if event.Event == EV_FIRE_WEAPON && event.Client != 0 && event.Client != 3 {
+				tst.Errorf("Unexpected weapon fire from client %v.", event.Client)
+			}
+			counts[event.Event] += 1
+		}
+	}
+	scores1, _ := strconv.Atoi(demoState.Config[CS_SCORES1])
+	scores2, _ := strconv.Atoi(demoState.Config[CS_SCORES2])
+	if counts[EV_OBITUARY] != scores1+scores2 {
+		tst.Errorf("Expected an obituary per frag but got %v for %v and %v.", counts[EV_OBITUARY], scores1, scores2)
+	}
+	if counts[EV_FIRE_WEAPON] == 0 || counts[EV_RAILTRAIL] == 0 || counts[EV_ITEM_PICKUP] == 0 {
+		tst.Errorf("Unexpected event counts %v.", counts)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/vincasmiliunas/quake-live-demo-reader
+
+go 1.23
@@ -0,0 +1,106 @@
+package qldemo
+
+import "context"
+
+// Handlers passed to DemoReader.Walk implement any subset of the interfaces
+// below. Returning an error from a callback aborts the walk; ErrStop does so
+// without Walk reporting an error. Either way the reader cannot be resumed.
+type GamestateHandler interface {
+	OnGamestate(gamestate *Gamestate) error
+}
+
+type CommandHandler interface {
+	OnCommand(command *Command) error
+}
+
+type SnapshotHandler interface {
+	OnSnapshot(snapshot *Snapshot) error
+}
+
+// Entity callbacks are made only for snapshots that are decoded, before the
+// snapshot is handled, and not for those reported as SnapshotDropped.
+type EntityUpdateHandler interface {
+	OnEntityUpdate(id int, entity *Entity) error
+}
+
+type EntityRemovedHandler interface {
+	OnEntityRemoved(id int) error
+}
+
+type ConfigStringHandler interface {
+	OnConfigString(index int, value string) error
+}
+
+// EventHandler receives every event, after the handler for its type. The
+// aggregators of this package implement it and start over on a gamestate or
+// a map restart.
+type EventHandler interface {
+	OnEvent(event Event) error
+}
+
+// EndHandler is called once the demo has been read to its end without error.
+type EndHandler interface {
+	OnEnd() error
+}
+
+/* -------------------------------------------- */
+func (self *DemoReader) Walk(handler interface{}) error {
+	self.handler = handler
+	defer func() { self.handler = nil }()
+
+	err := self.blockLoop(context.Background(), self.dispatch)
+	if handler, ok := handler.(EndHandler); ok && err == nil {
+		err = handler.OnEnd()
+	}
+	if err == ErrStop {
+		err = nil
+	}
+	self.err = err
+	return err
+}
+
+func (self *DemoReader) dispatch(entry Event) error {
+	var err error
+	switch entry := entry.(type) {
+	case *Gamestate:
+		if handler, ok := self.handler.(GamestateHandler); ok {
+			err = handler.OnGamestate(entry)
+		}
+	case *Command:
+		if handler, ok := self.handler.(CommandHandler); ok {
+			err = handler.OnCommand(entry)
+		}
+	case *Snapshot:
+		if handler, ok := self.handler.(SnapshotHandler); ok {
+			err = handler.OnSnapshot(entry)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if handler, ok := self.handler.(EventHandler); ok {
+		return handler.OnEvent(entry)
+	}
+	return nil
+}
+
+func (self *DemoReader) onEntityUpdate(id int, entity *Entity) error {
+	if handler, ok := self.handler.(EntityUpdateHandler); ok {
+		return handler.OnEntityUpdate(id, entity)
+	}
+	return nil
+}
+
+func (self *DemoReader) onEntityRemoved(id int) error {
+	if handler, ok := self.handler.(EntityRemovedHandler); ok {
+		return handler.OnEntityRemoved(id)
+	}
+	return nil
+}
+
+func (self *DemoReader) onConfigString(index int) error {
+	if handler, ok := self.handler.(ConfigStringHandler); ok {
+		return handler.OnConfigString(index, self.demoState.Config[index])
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func loadDuel(tst *testing.T) []byte {
+	raw, err := os.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Fatalf("Failed to load duel.dm_73: %v", err)
+	}
+	return raw
+}
+
+// replayDuel feeds every event of duel.dm_73 to handlers, reading it into
+// demoState, and ends them as Walk would.
+func replayDuel(tst *testing.T, demoState *DemoState, handlers ...EventHandler) {
+	reader := NewDemoReader(bytes.NewReader(loadDuel(tst)), demoState)
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		for _, handler := range handlers {
+			if err := handler.OnEvent(entry); err != nil {
+				tst.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	for _, handler := range handlers {
+		if handler, ok := handler.(EndHandler); ok {
+			if err := handler.OnEnd(); err != nil {
+				tst.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+}
+
+type stopHandler struct {
+	snapshots int
+	updates   int
+	configs   int
+}
+
+func (self *stopHandler) OnSnapshot(snapshot *Snapshot) error {
+	self.snapshots += 1
+	return nil
+}
+
+func (self *stopHandler) OnEntityUpdate(id int, entity *Entity) error {
+	self.updates += 1
+	return nil
+}
+
+func (self *stopHandler) OnConfigString(index int, value string) error {
+	self.configs += 1
+	return nil
+}
+
+func (self *stopHandler) OnCommand(command *Command) error {
+	if self.snapshots >= 100 {
+		return ErrStop
+	}
+	return nil
+}
+
+func TestWalk(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	handler := new(stopHandler)
+	if err := reader.Walk(handler); err != nil {
+		tst.Errorf("Unexpected error: %v", err)
+	}
+	if handler.snapshots == 0 || handler.updates == 0 || handler.configs == 0 {
+		tst.Errorf("Expected callbacks but got %+v.", *handler)
+	}
+	if handler.snapshots > 1000 {
+		tst.Errorf("Expected the walk to stop early but got %v snapshots.", handler.snapshots)
+	}
+}
+
+type retainHandler struct {
+	retained  []*Entity
+	copies    []Entity
+	demoState *DemoState
+	baselines map[int]Entity
+}
+
+func (self *retainHandler) OnGamestate(gamestate *Gamestate) error {
+	for id, entity := range self.demoState.EntityBaselines {
+		self.baselines[id] = *entity
+	}
+	return nil
+}
+
+func (self *retainHandler) OnEntityUpdate(id int, entity *Entity) error {
+	self.retained = append(self.retained, entity)
+	self.copies = append(self.copies, *entity)
+	return nil
+}
+
+func TestRetainedEntities(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	handler := &retainHandler{demoState: demoState, baselines: make(map[int]Entity)}
+	if err := reader.Walk(handler); err != nil {
+		tst.Errorf("Unexpected error: %v", err)
+	}
+
+	for i, entity := range handler.retained {
+		if *entity != handler.copies[i] {
+			tst.Fatalf("Retained entity %v changed after it was received.", i)
+		}
+	}
+	for id, entity := range demoState.EntityBaselines {
+		if *entity != handler.baselines[id] {
+			tst.Errorf("Baseline %v changed while reading snapshots.", id)
+		}
+	}
+}
+
+type droppedHandler struct {
+	pending int
+	dropped int
+	leaked  int
+}
+
+func (self *droppedHandler) OnEntityUpdate(id int, entity *Entity) error {
+	self.pending += 1
+	return nil
+}
+
+func (self *droppedHandler) OnEntityRemoved(id int) error {
+	self.pending += 1
+	return nil
+}
+
+func (self *droppedHandler) OnEvent(event Event) error {
+	switch event.(type) {
+	case *Snapshot:
+		self.pending = 0
+	case *SnapshotDropped:
+		self.dropped += 1
+		self.leaked += self.pending
+		self.pending = 0
+	}
+	return nil
+}
+
+func TestDroppedEntityCallbacks(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(gapped(raw)), NewDemoState())
+
+	handler := new(droppedHandler)
+	if err := reader.Walk(handler); err != nil {
+		tst.Errorf("Unexpected error: %v", err)
+	}
+	if handler.dropped == 0 || handler.leaked != 0 {
+		tst.Errorf("Expected no entity callbacks for %v dropped snapshots but got %v.", handler.dropped, handler.leaked)
+	}
+}
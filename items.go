@@ -0,0 +1,196 @@
+package qldemo
+
+import (
+	"fmt"
+	"sort"
+)
+
+type ItemType int
+
+const (
+	IT_BAD ItemType = iota
+	IT_WEAPON
+	IT_AMMO
+	IT_ARMOR
+	IT_HEALTH
+	IT_POWERUP
+	IT_HOLDABLE
+	IT_PERSISTANT_POWERUP
+	IT_TEAM
+)
+
+var itemTypeNames = map[ItemType]string{
+	IT_BAD:                "Bad",
+	IT_WEAPON:             "Weapon",
+	IT_AMMO:               "Ammo",
+	IT_ARMOR:              "Armor",
+	IT_HEALTH:             "Health",
+	IT_POWERUP:            "Powerup",
+	IT_HOLDABLE:           "Holdable",
+	IT_PERSISTANT_POWERUP: "Persistant Powerup",
+	IT_TEAM:               "Team",
+}
+
+func (self ItemType) String() string {
+	if name, ok := itemTypeNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("ItemType(%d)", int(self))
+}
+
+// ItemInfo is an entry of the protocol 73 bg_itemlist. Tag is the Weapon of
+// weapons and ammo, the Powerup of powerups and otherwise 0.
+type ItemInfo struct {
+	ClassName string
+	Name      string
+	Type      ItemType
+	Tag       int
+}
+
+var itemList = []ItemInfo{
+	{"", "None", IT_BAD, 0},
+	{"item_armor_shard", "Armor Shard", IT_ARMOR, 0},
+	{"item_armor_combat", "Yellow Armor", IT_ARMOR, 0},
+	{"item_armor_body", "Red Armor", IT_ARMOR, 0},
+	{"item_armor_jacket", "Green Armor", IT_ARMOR, 0},
+	{"item_health_small", "5 Health", IT_HEALTH, 0},
+	{"item_health", "25 Health", IT_HEALTH, 0},
+	{"item_health_large", "50 Health", IT_HEALTH, 0},
+	{"item_health_mega", "Mega Health", IT_HEALTH, 0},
+	{"weapon_gauntlet", "Gauntlet", IT_WEAPON, int(WP_GAUNTLET)},
+	{"weapon_shotgun", "Shotgun", IT_WEAPON, int(WP_SHOTGUN)},
+	{"weapon_machinegun", "Machinegun", IT_WEAPON, int(WP_MACHINEGUN)},
+	{"weapon_grenadelauncher", "Grenade Launcher", IT_WEAPON, int(WP_GRENADE_LAUNCHER)},
+	{"weapon_rocketlauncher", "Rocket Launcher", IT_WEAPON, int(WP_ROCKET_LAUNCHER)},
+	{"weapon_lightning", "Lightning Gun", IT_WEAPON, int(WP_LIGHTNING)},
+	{"weapon_railgun", "Railgun", IT_WEAPON, int(WP_RAILGUN)},
+	{"weapon_plasmagun", "Plasma Gun", IT_WEAPON, int(WP_PLASMAGUN)},
+	{"weapon_bfg", "BFG10K", IT_WEAPON, int(WP_BFG)},
+	{"weapon_grapplinghook", "Grappling Hook", IT_WEAPON, int(WP_GRAPPLING_HOOK)},
+	{"ammo_shells", "Shells", IT_AMMO, int(WP_SHOTGUN)},
+	{"ammo_bullets", "Bullets", IT_AMMO, int(WP_MACHINEGUN)},
+	{"ammo_grenades", "Grenades", IT_AMMO, int(WP_GRENADE_LAUNCHER)},
+	{"ammo_cells", "Cells", IT_AMMO, int(WP_PLASMAGUN)},
+	{"ammo_lightning", "Lightning", IT_AMMO, int(WP_LIGHTNING)},
+	{"ammo_rockets", "Rockets", IT_AMMO, int(WP_ROCKET_LAUNCHER)},
+	{"ammo_slugs", "Slugs", IT_AMMO, int(WP_RAILGUN)},
+	{"ammo_bfg", "Bfg Ammo", IT_AMMO, int(WP_BFG)},
+	{"holdable_teleporter", "Personal Teleporter", IT_HOLDABLE, 0},
+	{"holdable_medkit", "Medkit", IT_HOLDABLE, 0},
+	{"item_quad", "Quad Damage", IT_POWERUP, int(PW_QUAD)},
+	{"item_enviro", "Battle Suit", IT_POWERUP, int(PW_BATTLESUIT)},
+	{"item_haste", "Haste", IT_POWERUP, int(PW_HASTE)},
+	{"item_invis", "Invisibility", IT_POWERUP, int(PW_INVIS)},
+	{"item_regen", "Regeneration", IT_POWERUP, int(PW_REGEN)},
+	{"item_flight", "Flight", IT_POWERUP, int(PW_FLIGHT)},
+	{"team_CTF_redflag", "Red Flag", IT_TEAM, int(PW_REDFLAG)},
+	{"team_CTF_blueflag", "Blue Flag", IT_TEAM, int(PW_BLUEFLAG)},
+	{"holdable_kamikaze", "Kamikaze", IT_HOLDABLE, 0},
+	{"holdable_portal", "Portal", IT_HOLDABLE, 0},
+	{"holdable_invulnerability", "Invulnerability", IT_HOLDABLE, 0},
+	{"ammo_nails", "Nails", IT_AMMO, int(WP_NAILGUN)},
+	{"ammo_mines", "Proximity Mines", IT_AMMO, int(WP_PROX_LAUNCHER)},
+	{"ammo_belt", "Chaingun Belt", IT_AMMO, int(WP_CHAINGUN)},
+	{"item_scout", "Scout", IT_PERSISTANT_POWERUP, int(PW_SCOUT)},
+	{"item_guard", "Guard", IT_PERSISTANT_POWERUP, int(PW_GUARD)},
+	{"item_doubler", "Doubler", IT_PERSISTANT_POWERUP, int(PW_DOUBLER)},
+	{"item_armorregen", "Armor Regen", IT_PERSISTANT_POWERUP, int(PW_ARMORREGEN)},
+	{"team_CTF_neutralflag", "Neutral Flag", IT_TEAM, int(PW_NEUTRALFLAG)},
+	{"item_redcube", "Red Cube", IT_TEAM, 0},
+	{"item_bluecube", "Blue Cube", IT_TEAM, 0},
+	{"weapon_nailgun", "Nailgun", IT_WEAPON, int(WP_NAILGUN)},
+	{"weapon_prox_launcher", "Prox Launcher", IT_WEAPON, int(WP_PROX_LAUNCHER)},
+	{"weapon_chaingun", "Chaingun", IT_WEAPON, int(WP_CHAINGUN)},
+	{"item_spawnarmor", "Spawn Armor", IT_ARMOR, 0},
+	{"weapon_hmg", "Heavy Machinegun", IT_WEAPON, int(WP_HMG)},
+	{"ammo_hmg", "HMG Bullets", IT_AMMO, int(WP_HMG)},
+	{"ammo_pack", "Ammo Pack", IT_AMMO, 0},
+	{"item_key_silver", "Silver Key", IT_HOLDABLE, 0},
+	{"item_key_gold", "Gold Key", IT_HOLDABLE, 0},
+	{"item_key_master", "Master Key", IT_HOLDABLE, 0},
+}
+
+// Item is an index into bg_itemlist, as carried by the modelindex of ET_ITEM
+// entities and the parameter of pickup events.
+type Item int
+
+// Info returns the bg_itemlist entry of the item, or that of item 0 if it is
+// out of range.
+func (self Item) Info() *ItemInfo {
+	if self < 0 || int(self) >= len(itemList) {
+		return &itemList[0]
+	}
+	return &itemList[self]
+}
+
+func (self Item) String() string {
+	if self <= 0 || int(self) >= len(itemList) {
+		return fmt.Sprintf("Item(%d)", int(self))
+	}
+	return itemList[self].Name
+}
+
+/* -------------------------------------------- */
+// ItemPickup is a pickup announced by EV_ITEM_PICKUP or EV_GLOBAL_ITEM_PICKUP.
+// Client is -1 for global pickups by a client that was not visible, and
+// Origin is where the client or the global event was.
+type ItemPickup struct {
+	EventInfo
+	Time   int
+	Client int
+	Item   Item
+	Origin Vector
+}
+
+func (self *ItemPickup) Kind() EventKind {
+	return KIND_ITEM_PICKUP
+}
+
+// itemPickup returns the pickup announced by event, or nil if it is not one.
+// A global pickup is dropped if the same item was just seen picked up by a
+// visible client, since both events are sent for the same pickup.
+func (self *DemoState) itemPickup(event *GameEvent) *ItemPickup {
+	item, time := Item(event.Parm), event.ServerTime
+	switch event.Event {
+	case EV_ITEM_PICKUP:
+		self.pickups[item] = time
+		return &ItemPickup{EventInfo: event.EventInfo, Time: time, Client: event.Client, Item: item, Origin: event.Origin}
+	case EV_GLOBAL_ITEM_PICKUP:
+		if last, ok := self.pickups[item]; ok && time-last <= EVENT_VALID_MSEC {
+			return nil
+		}
+		return &ItemPickup{EventInfo: event.EventInfo, Time: time, Client: -1, Item: item, Origin: event.Origin}
+	}
+	return nil
+}
+
+// MapItem is an item placed on the map.
+type MapItem struct {
+	Entity int
+	Item   Item
+	Origin Vector
+}
+
+// MapItems returns the items placed on the map, from the ET_ITEM entity
+// baselines of the gamestate, ordered by entity number.
+func (self *DemoState) MapItems() []MapItem {
+	result := []MapItem{}
+	for id, entity := range self.EntityBaselines {
+		if entity.Type() == ET_ITEM {
+			result = append(result, MapItem{id, Item(entity.ModelIndex), entity.Pos.Base})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Entity < result[j].Entity })
+	return result
+}
+
+// RegisteredItems returns the items the server registered in CS_ITEMS.
+func (self *DemoState) RegisteredItems() []Item {
+	result := []Item{}
+	for index, flag := range self.Config[CS_ITEMS] {
+		if flag == '1' {
+			result = append(result, Item(index))
+		}
+	}
+	return result
+}
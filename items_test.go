@@ -0,0 +1,63 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDuelItems(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	megas := map[int]int{}
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		switch entry.Kind() {
+		case KIND_GAMESTATE:
+			items := demoState.MapItems()
+			if len(items) != 29 || items[0] != (MapItem{72, 16, Vector{522, 2, 272}}) {
+				tst.Errorf("Unexpected map items %v.", items)
+			}
+			registered := demoState.RegisteredItems()
+			expected := []Item{1, 2, 5, 6, 8, 9, 10, 11, 13, 15, 16, 19, 20, 22, 24, 25}
+			if !reflect.DeepEqual(registered, expected) {
+				tst.Errorf("Unexpected registered items %v.", registered)
+			}
+		case KIND_ITEM_PICKUP:
+			pickup := entry.(*ItemPickup)
+			if pickup.Item.String() == "Mega Health" {
+				megas[pickup.Client] += 1
+			}
+		}
+	}
+	if megas[0] != 2 || megas[3] != 3 {
+		tst.Errorf("Unexpected mega health pickups %v.", megas)
+	}
+}
+
+func TestItemPickup(tst *testing.T) {
+	demoState := NewDemoState()
+	pickup := demoState.itemPickup(&GameEvent{Event: EV_ITEM_PICKUP, Parm: 29, Client: 2})
+	if pickup == nil || pickup.Client != 2 || pickup.Item.String() != "Quad Damage" ||
+		pickup.Item.Info().Tag != int(PW_QUAD) {
+		tst.Errorf("Unexpected pickup %+v.", pickup)
+	}
+	if demoState.itemPickup(&GameEvent{Event: EV_GLOBAL_ITEM_PICKUP, Parm: 29}) != nil {
+		tst.Errorf("Expected the global pickup of a seen pickup to be dropped.")
+	}
+	global := &GameEvent{Event: EV_GLOBAL_ITEM_PICKUP, Parm: 29, EventInfo: EventInfo{ServerTime: 5000}}
+	if pickup := demoState.itemPickup(global); pickup == nil || pickup.Client != -1 {
+		tst.Errorf("Unexpected global pickup %+v.", pickup)
+	}
+	if Item(100).String() != "Item(100)" || Item(100).Info().Type != IT_BAD {
+		tst.Errorf("Unexpected out of range item.")
+	}
+}
@@ -0,0 +1,170 @@
+package qldemo
+
+import "fmt"
+
+const (
+	ENTITYNUM_NONE  = MAX_ENTITY_INDEX
+	ENTITYNUM_WORLD = MAX_ENTITY_INDEX - 1
+)
+
+type MeansOfDeath int
+
+const (
+	MOD_UNKNOWN MeansOfDeath = iota
+	MOD_SHOTGUN
+	MOD_GAUNTLET
+	MOD_MACHINEGUN
+	MOD_GRENADE
+	MOD_GRENADE_SPLASH
+	MOD_ROCKET
+	MOD_ROCKET_SPLASH
+	MOD_PLASMA
+	MOD_PLASMA_SPLASH
+	MOD_RAILGUN
+	MOD_LIGHTNING
+	MOD_BFG
+	MOD_BFG_SPLASH
+	MOD_WATER
+	MOD_SLIME
+	MOD_LAVA
+	MOD_CRUSH
+	MOD_TELEFRAG
+	MOD_FALLING
+	MOD_SUICIDE
+	MOD_TARGET_LASER
+	MOD_TRIGGER_HURT
+	MOD_NAIL
+	MOD_CHAINGUN
+	MOD_PROXIMITY_MINE
+	MOD_KAMIKAZE
+	MOD_JUICED
+	MOD_GRAPPLE
+	MOD_SWITCH_TEAMS
+	MOD_THAW
+	MOD_LIGHTNING_DISCHARGE
+	MOD_HMG
+	MOD_RAILGUN_HEADSHOT
+)
+
+var meansOfDeathNames = map[MeansOfDeath]string{
+	MOD_UNKNOWN:             "Unknown",
+	MOD_SHOTGUN:             "Shotgun",
+	MOD_GAUNTLET:            "Gauntlet",
+	MOD_MACHINEGUN:          "Machinegun",
+	MOD_GRENADE:             "Grenade",
+	MOD_GRENADE_SPLASH:      "Grenade Splash",
+	MOD_ROCKET:              "Rocket",
+	MOD_ROCKET_SPLASH:       "Rocket Splash",
+	MOD_PLASMA:              "Plasma",
+	MOD_PLASMA_SPLASH:       "Plasma Splash",
+	MOD_RAILGUN:             "Railgun",
+	MOD_LIGHTNING:           "Lightning",
+	MOD_BFG:                 "BFG",
+	MOD_BFG_SPLASH:          "BFG Splash",
+	MOD_WATER:               "Water",
+	MOD_SLIME:               "Slime",
+	MOD_LAVA:                "Lava",
+	MOD_CRUSH:               "Crush",
+	MOD_TELEFRAG:            "Telefrag",
+	MOD_FALLING:             "Falling",
+	MOD_SUICIDE:             "Suicide",
+	MOD_TARGET_LASER:        "Target Laser",
+	MOD_TRIGGER_HURT:        "Trigger Hurt",
+	MOD_NAIL:                "Nail",
+	MOD_CHAINGUN:            "Chaingun",
+	MOD_PROXIMITY_MINE:      "Proximity Mine",
+	MOD_KAMIKAZE:            "Kamikaze",
+	MOD_JUICED:              "Juiced",
+	MOD_GRAPPLE:             "Grapple",
+	MOD_SWITCH_TEAMS:        "Switch Teams",
+	MOD_THAW:                "Thaw",
+	MOD_LIGHTNING_DISCHARGE: "Lightning Discharge",
+	MOD_HMG:                 "HMG",
+	MOD_RAILGUN_HEADSHOT:    "Railgun Headshot",
+}
+
+func (self MeansOfDeath) String() string {
+	if name, ok := meansOfDeathNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("MeansOfDeath(%d)", int(self))
+}
+
+// Weapon returns the weapon that causes this means of death, or WP_NONE.
+func (self MeansOfDeath) Weapon() Weapon {
+	switch self {
+	case MOD_SHOTGUN:
+		return WP_SHOTGUN
+	case MOD_GAUNTLET:
+		return WP_GAUNTLET
+	case MOD_MACHINEGUN:
+		return WP_MACHINEGUN
+	case MOD_GRENADE, MOD_GRENADE_SPLASH:
+		return WP_GRENADE_LAUNCHER
+	case MOD_ROCKET, MOD_ROCKET_SPLASH:
+		return WP_ROCKET_LAUNCHER
+	case MOD_PLASMA, MOD_PLASMA_SPLASH:
+		return WP_PLASMAGUN
+	case MOD_RAILGUN, MOD_RAILGUN_HEADSHOT:
+		return WP_RAILGUN
+	case MOD_LIGHTNING, MOD_LIGHTNING_DISCHARGE:
+		return WP_LIGHTNING
+	case MOD_BFG, MOD_BFG_SPLASH:
+		return WP_BFG
+	case MOD_NAIL:
+		return WP_NAILGUN
+	case MOD_CHAINGUN:
+		return WP_CHAINGUN
+	case MOD_PROXIMITY_MINE:
+		return WP_PROX_LAUNCHER
+	case MOD_GRAPPLE:
+		return WP_GRAPPLING_HOOK
+	case MOD_HMG:
+		return WP_HMG
+	}
+	return WP_NONE
+}
+
+/* -------------------------------------------- */
+// Obituary is a death announced by an EV_OBITUARY event entity. Attacker is
+// ENTITYNUM_WORLD for deaths not caused by a client, and the players are
+// taken from the roster at the time of death and nil if the slot is empty.
+type Obituary struct {
+	EventInfo
+	Time           int
+	Attacker       int
+	Target         int
+	MeansOfDeath   MeansOfDeath
+	AttackerPlayer *PlayerInfo
+	TargetPlayer   *PlayerInfo
+}
+
+func (self *Obituary) Kind() EventKind {
+	return KIND_OBITUARY
+}
+
+// IsWorldKill reports whether the target was not killed by a client.
+func (self *Obituary) IsWorldKill() bool {
+	return self.Attacker < 0 || self.Attacker >= MAX_CLIENTS
+}
+
+// IsSuicide reports whether the target killed themselves; deaths to the
+// world are not suicides.
+func (self *Obituary) IsSuicide() bool {
+	return self.Attacker == self.Target && !self.IsWorldKill()
+}
+
+// obituary returns the obituary announced by event, or nil if it is not one.
+func (self *DemoState) obituary(event *GameEvent) *Obituary {
+	if event.Event != EV_OBITUARY || event.State == nil {
+		return nil
+	}
+	result := &Obituary{EventInfo: event.EventInfo, Time: event.ServerTime,
+		Attacker: event.State.OtherEntityNum2, Target: event.State.OtherEntityNum,
+		MeansOfDeath: MeansOfDeath(event.Parm)}
+	if !result.IsWorldKill() {
+		result.AttackerPlayer = self.Roster[result.Attacker]
+	}
+	result.TargetPlayer = self.Roster[result.Target]
+	return result
+}
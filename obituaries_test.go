@@ -0,0 +1,58 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDuelObituaries(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	kills := map[string]int{}
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		obituary, ok := entry.(*Obituary)
+		if !ok {
+			continue
+		}
+		if obituary.Time != obituary.ServerTime || obituary.TargetPlayer == nil {
+			tst.Errorf("Unexpected obituary %+v.", obituary)
+		} else if obituary.IsSuicide() || obituary.IsWorldKill() {
+			kills[obituary.TargetPlayer.CleanName] -= 1
+		} else if obituary.MeansOfDeath.Weapon() == WP_NONE {
+			tst.Errorf("Unexpected means of death %v.", obituary.MeansOfDeath)
+		} else {
+			kills[obituary.AttackerPlayer.CleanName] += 1
+		}
+	}
+	if kills["Ventz"] != 6 || kills["ZenAku"] != 9 {
+		tst.Errorf("Unexpected kills %v.", kills)
+	}
+}
+
+func TestObituary(tst *testing.T) {
+	demoState := NewDemoState()
+	demoState.OnBaselineConfig(CS_PLAYERS+2, `n\Foo\t\0`)
+	event := &GameEvent{Event: EV_OBITUARY, Parm: int(MOD_LAVA),
+		State: &Entity{OtherEntityNum: 2, OtherEntityNum2: ENTITYNUM_WORLD}}
+
+	obituary := demoState.obituary(event)
+	if !obituary.IsWorldKill() || obituary.IsSuicide() || obituary.AttackerPlayer != nil ||
+		obituary.TargetPlayer.Name != "Foo" || obituary.MeansOfDeath.String() != "Lava" {
+		tst.Errorf("Unexpected obituary %+v.", obituary)
+	}
+	event.State.OtherEntityNum2, event.Parm = 2, int(MOD_ROCKET_SPLASH)
+	if obituary := demoState.obituary(event); !obituary.IsSuicide() || obituary.IsWorldKill() {
+		tst.Errorf("Expected a suicide but got %+v.", obituary)
+	}
+	if demoState.obituary(&GameEvent{Event: EV_PAIN}) != nil {
+		tst.Errorf("Expected no obituary for a pain event.")
+	}
+}
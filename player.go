@@ -0,0 +1,245 @@
+package qldemo
+
+import "fmt"
+
+const (
+	MAX_STATS      = 16
+	MAX_PERSISTANT = 16
+	MAX_WEAPONS    = 16
+	MAX_POWERUPS   = 16
+	MAX_PS_EVENTS  = 2
+)
+
+type PMType int
+
+const (
+	PM_NORMAL PMType = iota
+	PM_NOCLIP
+	PM_SPECTATOR
+	PM_DEAD
+	PM_FREEZE
+	PM_INTERMISSION
+	PM_SPINTERMISSION
+)
+
+const (
+	PMF_DUCKED         = 1
+	PMF_JUMP_HELD      = 2
+	PMF_BACKWARDS_JUMP = 8
+	PMF_BACKWARDS_RUN  = 16
+	PMF_TIME_LAND      = 32
+	PMF_TIME_KNOCKBACK = 64
+	PMF_TIME_WATERJUMP = 256
+	PMF_RESPAWNED      = 512
+	PMF_USE_ITEM_HELD  = 1024
+	PMF_GRAPPLE_PULL   = 2048
+	PMF_FOLLOW         = 4096
+	PMF_SCOREBOARD     = 8192
+	PMF_INVULEXPAND    = 16384
+)
+
+type WeaponState int
+
+const (
+	WEAPON_READY WeaponState = iota
+	WEAPON_RAISING
+	WEAPON_DROPPING
+	WEAPON_FIRING
+)
+
+type Stat int
+
+const (
+	STAT_HEALTH Stat = iota
+	STAT_HOLDABLE_ITEM
+	STAT_RUNE
+	STAT_WEAPONS
+	STAT_ARMOR
+	STAT_BSKILL
+	STAT_CLIENTS_READY
+	STAT_MAX_HEALTH
+	STAT_SPINUP
+	STAT_FLIGHT_THRUST
+	STAT_MAX_FLIGHT_FUEL
+	STAT_CUR_FLIGHT_FUEL
+	STAT_FLIGHT_REFUEL
+	STAT_QUADKILLS
+	STAT_ARMORTYPE
+	STAT_KEY
+)
+
+type Persistant int
+
+const (
+	PERS_SCORE Persistant = iota
+	PERS_HITS
+	PERS_RANK
+	PERS_TEAM
+	PERS_SPAWN_COUNT
+	PERS_PLAYEREVENTS
+	PERS_ATTACKER
+	PERS_ATTACKEE_ARMOR
+	PERS_KILLED
+	PERS_IMPRESSIVE_COUNT
+	PERS_EXCELLENT_COUNT
+	PERS_DEFEND_COUNT
+	PERS_ASSIST_COUNT
+	PERS_GAUNTLET_FRAG_COUNT
+	PERS_CAPTURES
+)
+
+type Weapon int
+
+const (
+	WP_NONE Weapon = iota
+	WP_GAUNTLET
+	WP_MACHINEGUN
+	WP_SHOTGUN
+	WP_GRENADE_LAUNCHER
+	WP_ROCKET_LAUNCHER
+	WP_LIGHTNING
+	WP_RAILGUN
+	WP_PLASMAGUN
+	WP_BFG
+	WP_GRAPPLING_HOOK
+	WP_NAILGUN
+	WP_PROX_LAUNCHER
+	WP_CHAINGUN
+	WP_HMG
+	WP_NUM_WEAPONS
+)
+
+var weaponNames = map[Weapon]string{
+	WP_NONE:             "None",
+	WP_GAUNTLET:         "Gauntlet",
+	WP_MACHINEGUN:       "Machinegun",
+	WP_SHOTGUN:          "Shotgun",
+	WP_GRENADE_LAUNCHER: "Grenade Launcher",
+	WP_ROCKET_LAUNCHER:  "Rocket Launcher",
+	WP_LIGHTNING:        "Lightning Gun",
+	WP_RAILGUN:          "Railgun",
+	WP_PLASMAGUN:        "Plasma Gun",
+	WP_BFG:              "BFG10K",
+	WP_GRAPPLING_HOOK:   "Grappling Hook",
+	WP_NAILGUN:          "Nailgun",
+	WP_PROX_LAUNCHER:    "Proximity Launcher",
+	WP_CHAINGUN:         "Chaingun",
+	WP_HMG:              "Heavy Machinegun",
+}
+
+func (self Weapon) String() string {
+	if name, ok := weaponNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("Weapon(%d)", int(self))
+}
+
+type Powerup int
+
+const (
+	PW_NONE Powerup = iota
+	PW_REDFLAG
+	PW_BLUEFLAG
+	PW_NEUTRALFLAG
+	PW_QUAD
+	PW_BATTLESUIT
+	PW_HASTE
+	PW_INVIS
+	PW_REGEN
+	PW_FLIGHT
+	PW_INVULNERABILITY
+	PW_SCOUT
+	PW_GUARD
+	PW_DOUBLER
+	PW_ARMORREGEN
+	PW_FREEZE
+)
+
+var powerupNames = map[Powerup]string{
+	PW_NONE:            "None",
+	PW_REDFLAG:         "Red Flag",
+	PW_BLUEFLAG:        "Blue Flag",
+	PW_NEUTRALFLAG:     "Neutral Flag",
+	PW_QUAD:            "Quad Damage",
+	PW_BATTLESUIT:      "Battle Suit",
+	PW_HASTE:           "Haste",
+	PW_INVIS:           "Invisibility",
+	PW_REGEN:           "Regeneration",
+	PW_FLIGHT:          "Flight",
+	PW_INVULNERABILITY: "Invulnerability",
+	PW_SCOUT:           "Scout",
+	PW_GUARD:           "Guard",
+	PW_DOUBLER:         "Doubler",
+	PW_ARMORREGEN:      "Armor Regen",
+	PW_FREEZE:          "Freeze",
+}
+
+func (self Powerup) String() string {
+	if name, ok := powerupNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("Powerup(%d)", int(self))
+}
+
+/* -------------------------------------------- */
+// Stat, Pers, AmmoFor and HasPowerup take indexes that may come from the
+// wire unchecked and treat those out of range as 0.
+func (self *Player) Stat(stat Stat) int {
+	if stat < 0 || stat >= MAX_STATS {
+		return 0
+	}
+	return self.Stats[stat]
+}
+
+func (self *Player) Pers(persistant Persistant) int {
+	if persistant < 0 || persistant >= MAX_PERSISTANT {
+		return 0
+	}
+	return self.Persistant[persistant]
+}
+
+func (self *Player) Health() int {
+	return self.Stats[STAT_HEALTH]
+}
+
+func (self *Player) Armor() int {
+	return self.Stats[STAT_ARMOR]
+}
+
+func (self *Player) Score() int {
+	return self.Persistant[PERS_SCORE]
+}
+
+func (self *Player) Team() Team {
+	return Team(self.Persistant[PERS_TEAM])
+}
+
+func (self *Player) CurrentWeapon() Weapon {
+	return Weapon(self.Weapon)
+}
+
+func (self *Player) HasWeapon(weapon Weapon) bool {
+	return self.Stats[STAT_WEAPONS]&(1<<uint(weapon)) != 0
+}
+
+// AmmoFor returns the ammunition of weapon, -1 meaning unlimited.
+func (self *Player) AmmoFor(weapon Weapon) int {
+	if weapon < 0 || weapon >= MAX_WEAPONS {
+		return 0
+	}
+	return self.Ammo[weapon]
+}
+
+// HasPowerup reports whether powerup is held at the given server time;
+// flags do not expire and are stored as a non-zero value.
+func (self *Player) HasPowerup(powerup Powerup, time int) bool {
+	if powerup < 0 || powerup >= MAX_POWERUPS {
+		return false
+	}
+	expiry := self.Powerups[powerup]
+	switch powerup {
+	case PW_REDFLAG, PW_BLUEFLAG, PW_NEUTRALFLAG:
+		return expiry != 0
+	}
+	return expiry > time
+}
@@ -0,0 +1,45 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDuelPlayer(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	types := map[PMType]int{}
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		snapshot, ok := entry.(*Snapshot)
+		if !ok {
+			continue
+		}
+		if team := snapshot.Player.Team(); team != TEAM_FREE {
+			tst.Fatalf("Expected the duel player to be on %v but got %v.", TEAM_FREE, team)
+		}
+		types[snapshot.Player.PMType] += 1
+	}
+	if types[PM_NORMAL] == 0 || types[PM_DEAD] == 0 {
+		tst.Errorf("Expected normal and dead player states but got %v.", types)
+	}
+}
+
+func TestPlayerIndexes(tst *testing.T) {
+	player := &Player{}
+	player.Stats[STAT_HEALTH], player.Ammo[WP_HMG] = 100, 50
+	if player.Stat(STAT_HEALTH) != 100 || player.AmmoFor(WP_HMG) != 50 {
+		tst.Errorf("Unexpected stat or ammo values.")
+	}
+	if player.Stat(-1) != 0 || player.Pers(MAX_PERSISTANT) != 0 || player.AmmoFor(Weapon(255)) != 0 ||
+		player.HasPowerup(Powerup(31), 0) {
+		tst.Errorf("Expected out of range indexes to be treated as 0.")
+	}
+}
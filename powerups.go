@@ -0,0 +1,113 @@
+package qldemo
+
+// PowerupSpan is a period a client held a powerup or a flag. Expired is -1
+// while the powerup is still held.
+type PowerupSpan struct {
+	Client   int
+	Powerup  Powerup
+	Acquired int
+	Expired  int
+}
+
+func (self *PowerupSpan) Duration(time int) int {
+	if self.Expired >= 0 {
+		time = self.Expired
+	}
+	return time - self.Acquired
+}
+
+/* -------------------------------------------- */
+// PowerupTimeline follows the powerups held by every client: the recording
+// client through its player state and the others through the powerup bits of
+// their entities. Only visible time is counted: the spans of a client end
+// when it drops out of the snapshots or dies, and start again if it is seen
+// holding the powerup later.
+type PowerupTimeline struct {
+	Time  int
+	Spans []PowerupSpan
+	held  map[int]map[Powerup]int
+}
+
+func NewPowerupTimeline() *PowerupTimeline {
+	return &PowerupTimeline{Spans: []PowerupSpan{}, held: make(map[int]map[Powerup]int)}
+}
+
+func (self *PowerupTimeline) Reset() {
+	*self = *NewPowerupTimeline()
+}
+
+func (self *PowerupTimeline) OnEvent(event Event) error {
+	switch typed := event.(type) {
+	case *Gamestate, *MapRestart:
+		self.Reset()
+	case *Snapshot:
+		self.onSnapshot(typed)
+	case *Obituary:
+		for powerup := range self.held[typed.Target] {
+			self.expire(typed.Target, powerup, typed.Time)
+		}
+	}
+	return nil
+}
+
+func (self *PowerupTimeline) onSnapshot(snapshot *Snapshot) {
+	self.Time = snapshot.Time
+	visible := map[int]int{}
+	for id, entity := range snapshot.Entities {
+		if id < MAX_CLIENTS && entity.Type() == ET_PLAYER {
+			visible[id] = entity.Powerups
+		}
+	}
+	player := &snapshot.Player
+	visible[player.ClientNum] = 0
+	for powerup := PW_REDFLAG; powerup < MAX_POWERUPS; powerup += 1 {
+		if player.HasPowerup(powerup, snapshot.Time) {
+			visible[player.ClientNum] |= 1 << uint(powerup)
+		}
+	}
+
+	for client := 0; client < MAX_CLIENTS; client += 1 {
+		powerups, ok := visible[client]
+		if !ok {
+			for powerup := range self.held[client] {
+				self.expire(client, powerup, snapshot.Time)
+			}
+			continue
+		}
+		for powerup := PW_REDFLAG; powerup < MAX_POWERUPS; powerup += 1 {
+			_, held := self.held[client][powerup]
+			if has := powerups&(1<<uint(powerup)) != 0; has && !held {
+				self.acquire(client, powerup, snapshot.Time)
+			} else if !has && held {
+				self.expire(client, powerup, snapshot.Time)
+			}
+		}
+	}
+}
+
+func (self *PowerupTimeline) acquire(client int, powerup Powerup, time int) {
+	if self.held[client] == nil {
+		self.held[client] = make(map[Powerup]int)
+	}
+	self.held[client][powerup] = len(self.Spans)
+	self.Spans = append(self.Spans, PowerupSpan{client, powerup, time, -1})
+}
+
+func (self *PowerupTimeline) expire(client int, powerup Powerup, time int) {
+	self.Spans[self.held[client][powerup]].Expired = time
+	delete(self.held[client], powerup)
+}
+
+// Summary returns the milliseconds each client held each powerup, counting
+// powerups still held up to the latest snapshot.
+func (self *PowerupTimeline) Summary() map[int]map[Powerup]int {
+	result := map[int]map[Powerup]int{}
+	for i := range self.Spans {
+		span := &self.Spans[i]
+		if result[span.Client] == nil {
+			result[span.Client] = make(map[Powerup]int)
+		}
+		result[span.Client][span.Powerup] += span.Duration(self.Time)
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package qldemo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPowerupTimeline(tst *testing.T) {
+	timeline := NewPowerupTimeline()
+	snapshot := func(time int, quad int, entities map[int]*Entity) {
+		player := Player{ClientNum: 3}
+		player.Powerups[PW_QUAD] = quad
+		timeline.OnEvent(&Snapshot{Time: time, Player: player, Entities: entities})
+	}
+	flag := map[int]*Entity{0: {EType: int(ET_PLAYER), Powerups: 1 << PW_REDFLAG}}
+	snapshot(1000, 31000, flag)
+	snapshot(2000, 31000, map[int]*Entity{})
+	snapshot(31000, 31000, flag)
+	timeline.OnEvent(&Obituary{Time: 32000, Target: 0})
+	snapshot(33000, 31000, map[int]*Entity{})
+
+	expected := []PowerupSpan{{0, PW_REDFLAG, 1000, 2000}, {3, PW_QUAD, 1000, 31000}, {0, PW_REDFLAG, 31000, 32000}}
+	if !reflect.DeepEqual(timeline.Spans, expected) {
+		tst.Errorf("Unexpected spans %+v.", timeline.Spans)
+	}
+	snapshot(40000, 45000, map[int]*Entity{})
+	summary := timeline.Summary()
+	if summary[3][PW_QUAD] != 30000 || summary[0][PW_REDFLAG] != 2000 || timeline.Spans[3].Expired != -1 {
+		tst.Errorf("Unexpected summary %v.", summary)
+	}
+	timeline.OnEvent(&MapRestart{})
+	if len(timeline.Spans) != 0 {
+		tst.Errorf("Expected a map restart to reset the timeline.")
+	}
+}
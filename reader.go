@@ -1,10 +1,11 @@
-package main
+package qldemo
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"fmt"
 	"io"
+	"iter"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,6 +19,8 @@ const (
 	BYTE_MASK      = 0x07
 	BIT_MASK       = 0x01
 
+	MAX_MSGLEN = 16384
+
 	BITS_IN_MINIFLOAT    = 13
 	BITS_IN_ENTITY_INDEX = 10
 	MAX_ENTITY_INDEX     = (1 << BITS_IN_ENTITY_INDEX) - 1
@@ -25,9 +28,10 @@ const (
 
 /* -------------------------------------------- */
 type BitReader struct {
-	reader   *bytes.Reader
-	buffered byte
-	offset   int
+	reader     *bytes.Reader
+	buffered   byte
+	offset     int
+	overflowed bool
 }
 
 func NewBitReader(reader *bytes.Reader) *BitReader {
@@ -44,10 +48,17 @@ func (self *BitReader) Remaining() int {
 	return tmp1 + tmp2
 }
 
+func (self *BitReader) Overflowed() bool {
+	return self.overflowed
+}
+
 func (self *BitReader) Read() int {
 	bit_offset := uint(self.offset & BYTE_MASK)
 	if bit_offset == 0 {
-		self.buffered, _ = self.reader.ReadByte()
+		var err error
+		if self.buffered, err = self.reader.ReadByte(); err != nil {
+			self.overflowed = true
+		}
 	}
 	self.offset += 1
 	return int((self.buffered >> bit_offset) & BIT_MASK)
@@ -104,7 +115,7 @@ func (self *DataReader) ReadBits(count int) int {
 	return self.ReadBitsSub8(bits) | (self.ReadBytes(bytes) << uint(bits))
 }
 
-func (self *DataReader) ReadByte() int {
+func (self *DataReader) ReadUnsignedByte() int {
 	return self.decoder.Read()
 }
 
@@ -112,7 +123,7 @@ func (self *DataReader) ReadBytes(count int) int {
 	if count == 0 {
 		return 0
 	} else {
-		return self.ReadByte() | (self.ReadBytes(count-1) << BITS_IN_BYTE)
+		return self.ReadUnsignedByte() | (self.ReadBytes(count-1) << BITS_IN_BYTE)
 	}
 }
 
@@ -125,7 +136,7 @@ func (self *DataReader) ReadInt() int {
 }
 
 func (self *DataReader) ReadSignedByte() int {
-	return int(int8(self.ReadByte()))
+	return int(int8(self.ReadUnsignedByte()))
 }
 
 func (self *DataReader) ReadSignedShort() int {
@@ -148,7 +159,7 @@ func (self *DataReader) ReadFloat() float32 {
 func (self *DataReader) ReadString() string {
 	buffer := new(bytes.Buffer)
 	for {
-		tmp := self.ReadByte()
+		tmp := self.ReadUnsignedByte()
 		if tmp == 0 {
 			return string(buffer.Bytes())
 		}
@@ -159,7 +170,7 @@ func (self *DataReader) ReadString() string {
 func (self *DataReader) ReadBlob(count int) []byte {
 	buffer := new(bytes.Buffer)
 	for i := 0; i < count; i += 1 {
-		buffer.WriteByte(byte(self.ReadByte()))
+		buffer.WriteByte(byte(self.ReadUnsignedByte()))
 	}
 	return buffer.Bytes()
 }
@@ -185,8 +196,8 @@ func (self *StateReader) ReadBits(count int) int {
 	return self.ReadTemplate(func() int { return self.reader.ReadBits(count) })
 }
 
-func (self *StateReader) ReadByte() int {
-	return self.ReadTemplate(self.reader.ReadByte)
+func (self *StateReader) ReadUnsignedByte() int {
+	return self.ReadTemplate(self.reader.ReadUnsignedByte)
 }
 
 func (self *StateReader) ReadShort() int {
@@ -205,140 +216,147 @@ func (self *StateReader) ReadFloat() float32 {
 	}
 }
 
-func (self *StateReader) ReadValues(readers []func()) {
+func (self *StateReader) ReadValues(values []int, read func() int) {
 	if self.reader.ReadBit() == 0 {
 		return
 	}
 	flags := self.reader.ReadShort()
-	for i := uint(0); i < 16; i += 1 {
-		if flags&(1<<i) != 0 {
-			readers[i]()
+	for i := range values {
+		if flags&(1<<uint(i)) != 0 {
+			values[i] = read()
 		}
 	}
 }
 
-func (self *StateReader) ReadEntity(result *Entity) {
+func (self *StateReader) ReadEntity(result *Entity) error {
 	if self.reader.ReadBit() == 0 {
-		return
+		return nil
 	}
 
 	readers := []func(){
-		func() { result.Trajectories.A.Time = self.ReadInt() },
-		func() { result.Trajectories.A.Base.X = self.ReadFloat() },
-		func() { result.Trajectories.A.Base.Y = self.ReadFloat() },
-		func() { result.Trajectories.A.Delta.X = self.ReadFloat() },
-		func() { result.Trajectories.A.Delta.Y = self.ReadFloat() },
-		func() { result.Trajectories.A.Base.Z = self.ReadFloat() },
-		func() { result.Trajectories.B.Base.Y = self.ReadFloat() },
-		func() { result.Trajectories.A.Delta.Z = self.ReadFloat() },
-		func() { result.Trajectories.B.Base.X = self.ReadFloat() },
-		func() { result.Trajectories.A.Gravity = self.ReadInt() },
-		func() { result.Events.A = self.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Angles.B.Y = self.ReadFloat() },
-		func() { result.Entity.B = self.ReadByte() },
-		func() { result.Animations.A = self.ReadByte() },
-		func() { result.Events.B = self.ReadByte() },
-		func() { result.Animations.B = self.ReadByte() },
-		func() { result.Entities.A = self.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Trajectories.A.Mode = self.ReadByte() },
-		func() { result.Entity.A = self.ReadBits(19) },
-		func() { result.Entities.B = self.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Weapon = self.ReadByte() },
-		func() { result.Client = self.ReadByte() },
-		func() { result.Angles.A.Y = self.ReadFloat() },
-		func() { result.Trajectories.A.Duration = self.ReadInt() },
-		func() { result.Trajectories.B.Mode = self.ReadByte() },
-		func() { result.Origins.A.X = self.ReadFloat() },
-		func() { result.Origins.A.Y = self.ReadFloat() },
-		func() { result.Origins.A.Z = self.ReadFloat() },
-		func() { result.Misc.E = self.ReadBits(24) },
+		func() { result.Pos.Time = self.ReadInt() },
+		func() { result.Pos.Base.X = self.ReadFloat() },
+		func() { result.Pos.Base.Y = self.ReadFloat() },
+		func() { result.Pos.Delta.X = self.ReadFloat() },
+		func() { result.Pos.Delta.Y = self.ReadFloat() },
+		func() { result.Pos.Base.Z = self.ReadFloat() },
+		func() { result.APos.Base.Y = self.ReadFloat() },
+		func() { result.Pos.Delta.Z = self.ReadFloat() },
+		func() { result.APos.Base.X = self.ReadFloat() },
+		func() { result.Pos.Gravity = self.ReadInt() },
+		func() { result.Event = self.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.Angles2.Y = self.ReadFloat() },
+		func() { result.EType = self.ReadUnsignedByte() },
+		func() { result.TorsoAnim = self.ReadUnsignedByte() },
+		func() { result.EventParm = self.ReadUnsignedByte() },
+		func() { result.LegsAnim = self.ReadUnsignedByte() },
+		func() { result.GroundEntityNum = self.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.Pos.Type = self.ReadUnsignedByte() },
+		func() { result.EFlags = self.ReadBits(19) },
+		func() { result.OtherEntityNum = self.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.Weapon = self.ReadUnsignedByte() },
+		func() { result.ClientNum = self.ReadUnsignedByte() },
+		func() { result.Angles.Y = self.ReadFloat() },
+		func() { result.Pos.Duration = self.ReadInt() },
+		func() { result.APos.Type = self.ReadUnsignedByte() },
+		func() { result.Origin.X = self.ReadFloat() },
+		func() { result.Origin.Y = self.ReadFloat() },
+		func() { result.Origin.Z = self.ReadFloat() },
+		func() { result.Solid = self.ReadBits(24) },
 		func() { result.Powerups = self.ReadShort() },
-		func() { result.Model.A = self.ReadByte() },
-		func() { result.Entities.C = self.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Misc.D = self.ReadByte() },
-		func() { result.Misc.C = self.ReadByte() },
-		func() { result.Origins.B.Z = self.ReadFloat() },
-		func() { result.Origins.B.X = self.ReadFloat() },
-		func() { result.Origins.B.Y = self.ReadFloat() },
-		func() { result.Model.B = self.ReadByte() },
-		func() { result.Angles.A.X = self.ReadFloat() },
-		func() { result.Time.A = self.ReadInt() },
-		func() { result.Trajectories.B.Time = self.ReadInt() },
-		func() { result.Trajectories.B.Duration = self.ReadInt() },
-		func() { result.Trajectories.B.Base.Z = self.ReadFloat() },
-		func() { result.Trajectories.B.Delta.X = self.ReadFloat() },
-		func() { result.Trajectories.B.Delta.Y = self.ReadFloat() },
-		func() { result.Trajectories.B.Delta.Z = self.ReadFloat() },
-		func() { result.Trajectories.B.Gravity = self.ReadInt() },
-		func() { result.Time.B = self.ReadInt() },
-		func() { result.Angles.A.Z = self.ReadFloat() },
-		func() { result.Angles.B.X = self.ReadFloat() },
-		func() { result.Angles.B.Z = self.ReadFloat() },
-		func() { result.Misc.A = self.ReadInt() },
-		func() { result.Misc.B = self.ReadShort() },
-	}
-
-	count := self.reader.ReadByte()
+		func() { result.ModelIndex = self.ReadUnsignedByte() },
+		func() { result.OtherEntityNum2 = self.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.LoopSound = self.ReadUnsignedByte() },
+		func() { result.Generic1 = self.ReadUnsignedByte() },
+		func() { result.Origin2.Z = self.ReadFloat() },
+		func() { result.Origin2.X = self.ReadFloat() },
+		func() { result.Origin2.Y = self.ReadFloat() },
+		func() { result.ModelIndex2 = self.ReadUnsignedByte() },
+		func() { result.Angles.X = self.ReadFloat() },
+		func() { result.Time = self.ReadInt() },
+		func() { result.APos.Time = self.ReadInt() },
+		func() { result.APos.Duration = self.ReadInt() },
+		func() { result.APos.Base.Z = self.ReadFloat() },
+		func() { result.APos.Delta.X = self.ReadFloat() },
+		func() { result.APos.Delta.Y = self.ReadFloat() },
+		func() { result.APos.Delta.Z = self.ReadFloat() },
+		func() { result.APos.Gravity = self.ReadInt() },
+		func() { result.Time2 = self.ReadInt() },
+		func() { result.Angles.Z = self.ReadFloat() },
+		func() { result.Angles2.X = self.ReadFloat() },
+		func() { result.Angles2.Z = self.ReadFloat() },
+		func() { result.ConstantLight = self.ReadInt() },
+		func() { result.Frame = self.ReadShort() },
+	}
+
+	count := self.reader.ReadUnsignedByte()
+	if count > len(readers) {
+		return &ErrBadFieldCount{State: "entity", Count: count, Max: len(readers)}
+	}
 	for i := 0; i < count; i += 1 {
 		if self.reader.ReadBit() == 1 {
 			readers[i]()
 		}
 	}
+	return nil
 }
 
-func (self *StateReader) ReadPlayer(result *Player) {
+func (self *StateReader) ReadPlayer(result *Player) error {
 	readers := []func(){
-		func() { result.Time = self.reader.ReadInt() },
+		func() { result.CommandTime = self.reader.ReadInt() },
 		func() { result.Origin.X = self.reader.ReadFloat() },
 		func() { result.Origin.Y = self.reader.ReadFloat() },
-		func() { result.Misc.A = self.reader.ReadByte() },
+		func() { result.BobCycle = self.reader.ReadUnsignedByte() },
 		func() { result.Velocity.X = self.reader.ReadFloat() },
 		func() { result.Velocity.Y = self.reader.ReadFloat() },
-		func() { result.View.Y = self.reader.ReadFloat() },
-		func() { result.View.X = self.reader.ReadFloat() },
-		func() { result.Weapon.C = self.reader.ReadSignedShort() },
+		func() { result.ViewAngles.Y = self.reader.ReadFloat() },
+		func() { result.ViewAngles.X = self.reader.ReadFloat() },
+		func() { result.WeaponTime = self.reader.ReadSignedShort() },
 		func() { result.Origin.Z = self.reader.ReadFloat() },
 		func() { result.Velocity.Z = self.reader.ReadFloat() },
-		func() { result.Animations.B.B = self.reader.ReadByte() },
-		func() { result.Movement.C = self.reader.ReadSignedShort() },
-		func() { result.Event.A = self.reader.ReadShort() },
-		func() { result.Animations.A.A = self.reader.ReadByte() },
-		func() { result.Movement.A = self.reader.ReadBits(4) },
-		func() { result.Events.A = self.reader.ReadByte() },
-		func() { result.Animations.B.A = self.reader.ReadByte() },
-		func() { result.Events.B = self.reader.ReadByte() },
-		func() { result.Movement.B = self.reader.ReadShort() },
-		func() { result.Entities.A = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Weapon.B = self.reader.ReadBits(4) },
-		func() { result.Entity.A = self.reader.ReadShort() },
-		func() { result.External.A = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Misc.C = self.reader.ReadShort() },
-		func() { result.Misc.E = self.reader.ReadShort() },
-		func() { result.Delta.B = self.reader.ReadShort() },
-		func() { result.External.B = self.reader.ReadByte() },
-		func() { result.Misc.F = self.reader.ReadSignedByte() },
-		func() { result.Damage.B = self.reader.ReadByte() },
-		func() { result.Damage.D = self.reader.ReadByte() },
-		func() { result.Damage.C = self.reader.ReadByte() },
-		func() { result.Damage.A = self.reader.ReadByte() },
-		func() { result.Misc.C = self.reader.ReadByte() },
-		func() { result.Movement.D = self.reader.ReadByte() },
-		func() { result.Delta.A = self.reader.ReadShort() },
-		func() { result.Delta.C = self.reader.ReadShort() },
-		func() { result.Animations.A.B = self.reader.ReadBits(12) },
-		func() { result.Event.B = self.reader.ReadByte() },
-		func() { result.Event.C = self.reader.ReadByte() },
-		func() { result.Client = self.reader.ReadByte() },
-		func() { result.Weapon.A = self.reader.ReadBits(5) },
-		func() { result.View.Z = self.reader.ReadFloat() },
-		func() { result.Grapple.X = self.reader.ReadFloat() },
-		func() { result.Grapple.Y = self.reader.ReadFloat() },
-		func() { result.Grapple.Z = self.reader.ReadFloat() },
-		func() { result.Entities.B = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
-		func() { result.Misc.D = self.reader.ReadShort() },
-	}
-
-	count := self.reader.ReadByte()
+		func() { result.LegsTimer = self.reader.ReadUnsignedByte() },
+		func() { result.PMTime = self.reader.ReadSignedShort() },
+		func() { result.EventSequence = self.reader.ReadShort() },
+		func() { result.TorsoAnim = self.reader.ReadUnsignedByte() },
+		func() { result.MovementDir = self.reader.ReadBits(4) },
+		func() { result.Events[0] = self.reader.ReadUnsignedByte() },
+		func() { result.LegsAnim = self.reader.ReadUnsignedByte() },
+		func() { result.Events[1] = self.reader.ReadUnsignedByte() },
+		func() { result.PMFlags = self.reader.ReadShort() },
+		func() { result.GroundEntityNum = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.WeaponState = WeaponState(self.reader.ReadBits(4)) },
+		func() { result.EFlags = self.reader.ReadShort() },
+		func() { result.ExternalEvent = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.Gravity = self.reader.ReadShort() },
+		func() { result.Speed = self.reader.ReadShort() },
+		func() { result.DeltaAngles[1] = self.reader.ReadShort() },
+		func() { result.ExternalEventParm = self.reader.ReadUnsignedByte() },
+		func() { result.ViewHeight = self.reader.ReadSignedByte() },
+		func() { result.DamageEvent = self.reader.ReadUnsignedByte() },
+		func() { result.DamageYaw = self.reader.ReadUnsignedByte() },
+		func() { result.DamagePitch = self.reader.ReadUnsignedByte() },
+		func() { result.DamageCount = self.reader.ReadUnsignedByte() },
+		func() { result.Generic1 = self.reader.ReadUnsignedByte() },
+		func() { result.PMType = PMType(self.reader.ReadUnsignedByte()) },
+		func() { result.DeltaAngles[0] = self.reader.ReadShort() },
+		func() { result.DeltaAngles[2] = self.reader.ReadShort() },
+		func() { result.TorsoTimer = self.reader.ReadBits(12) },
+		func() { result.EventParms[0] = self.reader.ReadUnsignedByte() },
+		func() { result.EventParms[1] = self.reader.ReadUnsignedByte() },
+		func() { result.ClientNum = self.reader.ReadUnsignedByte() },
+		func() { result.Weapon = self.reader.ReadBits(5) },
+		func() { result.ViewAngles.Z = self.reader.ReadFloat() },
+		func() { result.GrapplePoint.X = self.reader.ReadFloat() },
+		func() { result.GrapplePoint.Y = self.reader.ReadFloat() },
+		func() { result.GrapplePoint.Z = self.reader.ReadFloat() },
+		func() { result.JumppadEnt = self.reader.ReadBits(BITS_IN_ENTITY_INDEX) },
+		func() { result.LoopSound = self.reader.ReadShort() },
+	}
+
+	count := self.reader.ReadUnsignedByte()
+	if count > len(readers) {
+		return &ErrBadFieldCount{State: "player", Count: count, Max: len(readers)}
+	}
 	for i := 0; i < count; i += 1 {
 		if self.reader.ReadBit() == 1 {
 			readers[i]()
@@ -346,297 +364,509 @@ func (self *StateReader) ReadPlayer(result *Player) {
 	}
 
 	if self.reader.ReadBit() == 1 {
-		{
-			readers := []func(){
-				func() { result.Vitals.A = self.reader.ReadSignedShort() },
-				func() { result.Vitals.B = self.reader.ReadSignedShort() },
-				func() { result.Vitals.C = self.reader.ReadSignedShort() },
-				func() { result.Vitals.D = self.reader.ReadSignedShort() },
-				func() { result.Vitals.E = self.reader.ReadSignedShort() },
-				func() { result.Vitals.F = self.reader.ReadSignedShort() },
-				func() { result.Vitals.G = self.reader.ReadSignedShort() },
-				func() { result.Vitals.H = self.reader.ReadSignedShort() },
-				func() { result.Vitals.I = self.reader.ReadSignedShort() },
-				func() { result.Vitals.J = self.reader.ReadSignedShort() },
-				func() { result.Vitals.K = self.reader.ReadSignedShort() },
-				func() { result.Vitals.L = self.reader.ReadSignedShort() },
-				func() { result.Vitals.M = self.reader.ReadSignedShort() },
-				func() { result.Vitals.N = self.reader.ReadSignedShort() },
-				func() { result.Vitals.O = self.reader.ReadSignedShort() },
-				func() { result.Vitals.P = self.reader.ReadSignedShort() },
-			}
-			self.ReadValues(readers)
-		}
-
-		{
-			readers := []func(){
-				func() { result.Attributes.A = self.reader.ReadShort() },
-				func() { result.Attributes.B = self.reader.ReadShort() },
-				func() { result.Attributes.C = self.reader.ReadShort() },
-				func() { result.Attributes.D = self.reader.ReadShort() },
-				func() { result.Attributes.E = self.reader.ReadShort() },
-				func() { result.Attributes.F = self.reader.ReadShort() },
-				func() { result.Attributes.G = self.reader.ReadShort() },
-				func() { result.Attributes.H = self.reader.ReadShort() },
-				func() { result.Attributes.I = self.reader.ReadShort() },
-				func() { result.Attributes.J = self.reader.ReadShort() },
-				func() { result.Attributes.K = self.reader.ReadShort() },
-				func() { result.Attributes.L = self.reader.ReadShort() },
-				func() { result.Attributes.M = self.reader.ReadShort() },
-				func() { result.Attributes.N = self.reader.ReadShort() },
-				func() { result.Attributes.O = self.reader.ReadShort() },
-				func() { result.Attributes.P = self.reader.ReadShort() },
-			}
-			self.ReadValues(readers)
-		}
-
-		{
-			readers := []func(){
-				func() { result.Ammunition.A = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.B = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.C = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.D = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.E = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.F = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.G = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.H = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.I = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.J = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.K = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.L = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.M = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.N = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.O = self.reader.ReadSignedShort() },
-				func() { result.Ammunition.P = self.reader.ReadSignedShort() },
-			}
-			self.ReadValues(readers)
-		}
-
-		{
-			readers := []func(){
-				func() { result.Powerups.A = self.reader.ReadInt() },
-				func() { result.Powerups.B = self.reader.ReadInt() },
-				func() { result.Powerups.C = self.reader.ReadInt() },
-				func() { result.Powerups.D = self.reader.ReadInt() },
-				func() { result.Powerups.E = self.reader.ReadInt() },
-				func() { result.Powerups.F = self.reader.ReadInt() },
-				func() { result.Powerups.G = self.reader.ReadInt() },
-				func() { result.Powerups.H = self.reader.ReadInt() },
-				func() { result.Powerups.I = self.reader.ReadInt() },
-				func() { result.Powerups.J = self.reader.ReadInt() },
-				func() { result.Powerups.K = self.reader.ReadInt() },
-				func() { result.Powerups.L = self.reader.ReadInt() },
-				func() { result.Powerups.M = self.reader.ReadInt() },
-				func() { result.Powerups.N = self.reader.ReadInt() },
-				func() { result.Powerups.O = self.reader.ReadInt() },
-				func() { result.Powerups.P = self.reader.ReadInt() },
-			}
-			self.ReadValues(readers)
-		}
+		self.ReadValues(result.Stats[:], self.reader.ReadSignedShort)
+		self.ReadValues(result.Persistant[:], self.reader.ReadSignedShort)
+		self.ReadValues(result.Ammo[:], self.reader.ReadSignedShort)
+		self.ReadValues(result.Powerups[:], self.reader.ReadInt)
 	}
+	return nil
 }
 
 /* -------------------------------------------- */
+const (
+	PACKET_BACKUP = 32
+	PACKET_MASK   = PACKET_BACKUP - 1
+)
+
+// Decoded entities are never modified: a changed entity is copied, so frames
+// share the entities they have in common and *Entity values may be retained.
+type frame struct {
+	valid      bool
+	messageNum int
+	player     Player
+	entities   map[int]*Entity
+}
+
+// DemoState holds the state of the latest valid snapshot. Entities and
+// EntityBaselines must not be modified by consumers; Entities is replaced,
+// not updated, by every snapshot.
 type DemoState struct {
 	Player          Player
 	Entities        map[int]*Entity
 	EntityBaselines map[int]*Entity
 	Config          map[int]string
+	Roster          Roster
+	CommandSequence int
 	configTmp       map[int]string
+	frames          [PACKET_BACKUP]frame
+	events          eventState
+	pickups         map[Item]int
 }
 
 func NewDemoState() *DemoState {
 	return &DemoState{Entities: make(map[int]*Entity), EntityBaselines: make(map[int]*Entity),
-		Config: make(map[int]string), configTmp: make(map[int]string)}
+		Config: make(map[int]string), Roster: make(Roster), configTmp: make(map[int]string),
+		pickups: make(map[Item]int)}
 }
 
 func (self *DemoState) OnBaselineConfig(id int, str string) {
 	self.Config[id] = str
+	self.updateRoster(id)
 }
 
 func (self *DemoState) OnBaselineEntity(id int, entity *Entity) {
-	self.Entities[id] = entity
-	self.EntityBaselines[id] = entity
-}
-
-func (self *DemoState) OnEntityRemoved(id int) {
-	delete(self.Entities, id)
+	baseline := *entity
+	self.EntityBaselines[id] = &baseline
+}
+
+func (self *DemoState) OnGamestate(commandSequence int) {
+	self.CommandSequence = commandSequence
+	self.Config = make(map[int]string)
+	self.Roster = make(Roster)
+	self.configTmp = make(map[int]string)
+	self.Entities = make(map[int]*Entity)
+	self.EntityBaselines = make(map[int]*Entity)
+	self.frames = [PACKET_BACKUP]frame{}
+	self.events = eventState{}
+	self.pickups = make(map[Item]int)
+}
+
+// deltaFrame returns the frame that snapshot messageNum is delta compressed
+// against, or nil if it is not. The frame is returned even when it is
+// unusable so that the snapshot can still be read past.
+func (self *DemoState) deltaFrame(messageNum, delta int) (*frame, error) {
+	if delta == 0 {
+		return nil, nil
+	}
+	old := &self.frames[(messageNum-delta)&PACKET_MASK]
+	if !old.valid {
+		return old, ErrDeltaInvalid
+	} else if old.messageNum != messageNum-delta {
+		return old, ErrDeltaTooOld
+	}
+	return old, nil
 }
 
-func (self *DemoState) OnEntityUpdate(id int) {
-	if _, ok := self.Entities[id]; !ok {
-		self.Entities[id] = new(Entity)
-	}
-	if _, ok := self.EntityBaselines[id]; ok {
-		*self.Entities[id] = *self.EntityBaselines[id]
+func (self *DemoState) onFrame(current *frame) {
+	self.frames[current.messageNum&PACKET_MASK] = *current
+	self.Player = current.player
+	self.Entities = make(map[int]*Entity, len(current.entities))
+	for id, entity := range current.entities {
+		self.Entities[id] = entity
 	}
 }
 
 var csRegexp = regexp.MustCompile(`(?ms)^cs (\d+) "(.*?)".*?$`)
 var bcsRegexp = regexp.MustCompile(`(?ms)^bcs(\d) (\d+) "(.+?)".*?$`)
 
-func (self *DemoState) OnMessageCommand(id int, str string) {
+// OnMessageCommand applies a config string command and returns the change
+// it made, or nil if it made none. Commands are retransmitted until they are
+// acknowledged, so those at or below CommandSequence are ignored.
+func (self *DemoState) OnMessageCommand(id int, str string) (*ConfigStringChanged, error) {
+	if id <= self.CommandSequence {
+		return nil, nil
+	}
+	self.CommandSequence = id
+
 	if matches := csRegexp.FindStringSubmatch(str); matches != nil {
-		code, _ := strconv.Atoi(matches[0])
-		self.Config[code] = matches[1]
-	} else if matches := bcsRegexp.FindStringSubmatch(str); matches != nil {
-		index, _ := strconv.Atoi(matches[0])
 		code, _ := strconv.Atoi(matches[1])
+		return self.setConfig(code, matches[2]), nil
+	} else if matches := bcsRegexp.FindStringSubmatch(str); matches != nil {
+		index, _ := strconv.Atoi(matches[1])
+		code, _ := strconv.Atoi(matches[2])
+		part, started := self.configTmp[code]
 		if index == 0 {
-			self.configTmp[code] = matches[2]
+			self.configTmp[code] = matches[3]
+		} else if index <= 2 && started {
+			self.configTmp[code] = strings.Join([]string{part, matches[3]}, "")
 		} else {
-			self.configTmp[code] = strings.Join([]string{self.configTmp[code], matches[2]}, "")
+			delete(self.configTmp, code)
+			return nil, &ErrBadBigConfigString{Index: code, Part: index}
 		}
-		if index == 3 {
-			self.Config[code] = self.configTmp[code]
+		if index == 2 {
+			value := self.configTmp[code]
 			delete(self.configTmp, code)
-		} else if index > 3 {
-			panic("index cannot be larger than 3")
+			return self.setConfig(code, value), nil
 		}
 	}
+	return nil, nil
+}
+
+func (self *DemoState) setConfig(index int, value string) *ConfigStringChanged {
+	old := self.Config[index]
+	self.Config[index] = value
+	return &ConfigStringChanged{Index: index, Old: old, New: value}
 }
 
 /* -------------------------------------------- */
 type DemoReader struct {
 	reader      io.Reader
 	demoState   *DemoState
+	bitReader   *BitReader
 	dataReader  *DataReader
 	stateReader *StateReader
+	offset      int
+	blockOffset int
+	blockId     int
+	messageId   int
+	serverTime  int
+	handler     interface{}
+	stopped     bool
+	err         error
 }
 
 func NewDemoReader(reader io.Reader, demoState *DemoState) *DemoReader {
 	return &DemoReader{reader: reader, demoState: demoState}
 }
 
-func (self *DemoReader) ReadBytes(count int) []byte {
+func (self *DemoReader) Err() error {
+	return self.err
+}
+
+func (self *DemoReader) ReadBytes(count int) ([]byte, error) {
 	buffer := make([]byte, count)
 	for count > 0 {
 		read, err := self.reader.Read(buffer[len(buffer)-count:])
-		if err != nil {
-			panic("Reading failed")
+		count -= read
+		self.offset += read
+		if count == 0 {
+			break
 		}
-		if read == 0 && len(buffer) == count {
-			return nil
+		if err == io.EOF && count == len(buffer) {
+			return nil, io.EOF
+		} else if err == io.EOF {
+			return nil, ErrTruncated
+		} else if err != nil {
+			return nil, err
 		}
-		count -= read
 	}
-	return buffer
+	return buffer, nil
 }
 
-func (self *DemoReader) ReadInt() (bool, int) {
-	tmp := self.ReadBytes(BYTES_IN_INT)
-	if tmp == nil {
-		return false, 0
-	} else {
-		var ret int32
-		binary.Read(bytes.NewReader(tmp), binary.LittleEndian, &ret)
-		return true, int(ret)
+func (self *DemoReader) ReadInt() (int, error) {
+	tmp, err := self.ReadBytes(BYTES_IN_INT)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(binary.LittleEndian.Uint32(tmp))), nil
+}
+
+func (self *DemoReader) ReadBlock() ([]byte, error) {
+	length, err := self.ReadInt()
+	if err == io.EOF {
+		return nil, ErrTruncated
+	} else if err != nil {
+		return nil, err
+	} else if length == -1 {
+		return nil, io.EOF
+	} else if length < 0 || length > MAX_MSGLEN {
+		return nil, &ErrBadBlockLength{Offset: self.offset - BYTES_IN_INT, Length: length}
 	}
+	block, err := self.ReadBytes(length)
+	if err == io.EOF {
+		return nil, ErrTruncated
+	}
+	return block, err
 }
 
-func (self *DemoReader) ReadBlock() []byte {
-	ok, length := self.ReadInt()
-	if !ok || length == -1 {
-		return nil
-	} else {
-		return self.ReadBytes(length)
+func (self *DemoReader) BlockLoop(channel chan Event) error {
+	return self.blockLoopContext(context.Background(), channel)
+}
+
+func (self *DemoReader) blockLoopContext(ctx context.Context, channel chan Event) error {
+	emit := func(entry Event) error {
+		select {
+		case channel <- entry:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	err := self.blockLoop(ctx, emit)
+	self.err = err
+	close(channel)
+	return err
 }
 
-func (self *DemoReader) BlockLoop(channel chan interface{}) {
+// blockLoop reads the remaining blocks. Reading stops for good on an error,
+// including ErrStop, as the rest of the current block is lost.
+func (self *DemoReader) blockLoop(ctx context.Context, emit func(Event) error) error {
+	if self.stopped {
+		return ErrStopped
+	}
+	err := self.readBlocks(ctx, emit)
+	self.stopped = err != nil
+	return err
+}
+
+func (self *DemoReader) readBlocks(ctx context.Context, emit func(Event) error) error {
 	for {
-		ok, blockId := self.ReadInt()
-		if !ok {
-			close(channel)
-			return
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		block := self.ReadBlock()
-		if block == nil {
-			close(channel)
-			return
+		blockOffset := self.offset
+		blockId, err := self.ReadInt()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		block, err := self.ReadBlock()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
 		}
 
-		bitReader := NewBitReader(bytes.NewReader(block))
-		self.dataReader = NewDataReader(bitReader)
+		self.blockOffset = blockOffset
+		self.bitReader = NewBitReader(bytes.NewReader(block))
+		self.dataReader = NewDataReader(self.bitReader)
 		self.stateReader = NewStateReader(self.dataReader)
 
 		messageId := self.dataReader.ReadInt()
-		self.MessageLoop(channel, blockId, messageId)
+		if err := self.MessageLoop(emit, blockId, messageId); err != nil {
+			return err
+		}
 	}
 }
 
-func (self *DemoReader) MessageLoop(channel chan interface{}, blockId, messageId int) {
+func (self *DemoReader) unknownOpcode(loop string, code int) error {
+	if self.bitReader.Overflowed() {
+		return ErrTruncated
+	}
+	return &ErrUnknownOpcode{Loop: loop, Code: code, BlockOffset: self.blockOffset,
+		BitOffset: self.bitReader.Position()}
+}
+
+func (self *DemoReader) info() EventInfo {
+	return EventInfo{BlockId: self.blockId, MessageId: self.messageId,
+		ServerTime: self.serverTime, Offset: self.blockOffset}
+}
+
+func (self *DemoReader) MessageLoop(emit func(Event) error, blockId, messageId int) error {
+	self.blockId, self.messageId = blockId, messageId
 	for {
-		switch tmp := self.dataReader.ReadByte(); tmp {
+		if self.bitReader.Overflowed() {
+			return ErrTruncated
+		}
+		switch tmp := self.dataReader.ReadUnsignedByte(); tmp {
 		case 1:
 		case 2:
 			id := self.dataReader.ReadInt()
-			client, checksum := self.GamestateLoop()
-			channel <- &Gamestate{id, client, checksum}
+			self.demoState.OnGamestate(id)
+			client, checksum, err := self.GamestateLoop()
+			if err != nil {
+				return err
+			}
+			if err := emit(&Gamestate{self.info(), id, client, checksum}); err != nil {
+				return err
+			}
 		case 5:
 			id := self.dataReader.ReadInt()
 			str := self.dataReader.ReadString()
-			self.demoState.OnMessageCommand(id, str)
-			channel <- &Command{id, str}
+			fresh := id > self.demoState.CommandSequence
+			change, err := self.demoState.OnMessageCommand(id, str)
+			if err != nil {
+				return err
+			}
+			if err := emit(&Command{self.info(), id, str}); err != nil {
+				return err
+			}
+			if fresh {
+				typed := self.demoState.command(str)
+				*typed.Info() = self.info()
+				if err := emit(typed); err != nil {
+					return err
+				}
+			}
+			if change != nil {
+				change.EventInfo = self.info()
+				if err := self.onConfigString(change.Index); err != nil {
+					return err
+				}
+				if err := emit(change); err != nil {
+					return err
+				}
+				if entry := self.demoState.updateRoster(change.Index); entry != nil {
+					*entry.Info() = self.info()
+					if err := emit(entry); err != nil {
+						return err
+					}
+				}
+			}
 		case 7:
 			time := self.dataReader.ReadInt()
-			delta := self.dataReader.ReadByte()
-			flags := self.dataReader.ReadByte()
-			blob_len := self.dataReader.ReadByte()
+			delta := self.dataReader.ReadUnsignedByte()
+			flags := self.dataReader.ReadUnsignedByte()
+			blob_len := self.dataReader.ReadUnsignedByte()
 			blob := self.dataReader.ReadBlob(blob_len)
-			self.stateReader.ReadPlayer(&self.demoState.Player)
-			self.SnapshotLoop()
-			channel <- &Snapshot{time, delta, flags, blob}
+			old, deltaErr := self.demoState.deltaFrame(self.blockId, delta)
+			current := &frame{messageNum: self.blockId, entities: make(map[int]*Entity)}
+			if old != nil {
+				current.player = old.player
+				for id, entity := range old.entities {
+					current.entities[id] = entity
+				}
+			}
+			if err := self.stateReader.ReadPlayer(&current.player); err != nil {
+				return err
+			}
+			changed, err := self.SnapshotLoop(current.entities)
+			if err != nil {
+				return err
+			}
+			if deltaErr != nil {
+				if err := emit(&SnapshotDropped{self.info(), time, self.blockId - delta, deltaErr}); err != nil {
+					return err
+				}
+				continue
+			}
+			current.valid = true
+			self.demoState.onFrame(current)
+			if err := self.onEntities(current.entities, changed); err != nil {
+				return err
+			}
+			self.serverTime = time
+			snapshot := &Snapshot{EventInfo: self.info(), Time: time, Delta: delta, Flags: flags,
+				Blob: blob, Player: current.player, Entities: self.demoState.Entities}
+			if err := emit(snapshot); err != nil {
+				return err
+			}
+			for _, event := range self.demoState.gameEvents(snapshot) {
+				event.EventInfo = self.info()
+				if err := emit(event); err != nil {
+					return err
+				}
+				if obituary := self.demoState.obituary(event); obituary != nil {
+					if err := emit(obituary); err != nil {
+						return err
+					}
+				}
+				if pickup := self.demoState.itemPickup(event); pickup != nil {
+					if err := emit(pickup); err != nil {
+						return err
+					}
+				}
+			}
 		case 8:
-			return
+			return nil
 		default:
-			panic(fmt.Sprintf("Invalid message loop code: %v", tmp))
+			return self.unknownOpcode("message", tmp)
 		}
 	}
 }
 
-func (self *DemoReader) GamestateLoop() (int, int) {
+func (self *DemoReader) GamestateLoop() (int, int, error) {
 	for {
-		switch tmp := self.dataReader.ReadByte(); tmp {
+		if self.bitReader.Overflowed() {
+			return 0, 0, ErrTruncated
+		}
+		switch tmp := self.dataReader.ReadUnsignedByte(); tmp {
 		case 3:
 			id := self.dataReader.ReadShort()
 			str := self.dataReader.ReadString()
 			self.demoState.OnBaselineConfig(id, str)
+			if err := self.onConfigString(id); err != nil {
+				return 0, 0, err
+			}
 		case 4:
 			id := self.dataReader.ReadBits(BITS_IN_ENTITY_INDEX)
 			entity := new(Entity)
 			if self.dataReader.ReadBit() == 0 {
-				self.stateReader.ReadEntity(entity)
+				if err := self.stateReader.ReadEntity(entity); err != nil {
+					return 0, 0, err
+				}
 			}
 			self.demoState.OnBaselineEntity(id, entity)
 		case 8:
 			client := self.dataReader.ReadInt()
 			checksum := self.dataReader.ReadInt()
-			return client, checksum
+			return client, checksum, nil
 		default:
-			panic(fmt.Sprintf("Invalid gamestate loop code: %v", tmp))
+			return 0, 0, self.unknownOpcode("gamestate", tmp)
 		}
 	}
 }
 
-func (self *DemoReader) SnapshotLoop() {
+// SnapshotLoop applies the entity deltas of a snapshot to entities, which
+// holds the entities of the delta frame, and returns the ids it changed.
+// Entities that are not in it are delta compressed against their baselines.
+func (self *DemoReader) SnapshotLoop(entities map[int]*Entity) ([]int, error) {
+	changed := []int{}
 	for {
 		id := self.dataReader.ReadBits(BITS_IN_ENTITY_INDEX)
-		if id == MAX_ENTITY_INDEX {
-			return
+		if self.bitReader.Overflowed() {
+			return nil, ErrTruncated
+		} else if id == MAX_ENTITY_INDEX {
+			return changed, nil
 		}
+		changed = append(changed, id)
 		if self.dataReader.ReadBit() == 1 {
-			self.demoState.OnEntityRemoved(id)
+			delete(entities, id)
+		} else {
+			base, ok := entities[id]
+			if !ok {
+				base = self.demoState.EntityBaselines[id]
+			}
+			entity := new(Entity)
+			if base != nil {
+				*entity = *base
+			}
+			if err := self.stateReader.ReadEntity(entity); err != nil {
+				return nil, err
+			}
+			entities[id] = entity
+		}
+	}
+}
+
+// onEntities reports the entities changed by a committed snapshot.
+func (self *DemoReader) onEntities(entities map[int]*Entity, changed []int) error {
+	for _, id := range changed {
+		var err error
+		if entity, ok := entities[id]; ok {
+			err = self.onEntityUpdate(id, entity)
 		} else {
-			self.demoState.OnEntityUpdate(id)
-			self.stateReader.ReadEntity(self.demoState.Entities[id])
+			err = self.onEntityRemoved(id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Iterate reads the demo in a goroutine that blocks until its events are
+// received, so the channel must be drained; use IterateContext to stop early.
+func (self *DemoReader) Iterate() chan Event {
+	return self.IterateContext(context.Background())
+}
+
+// Events reads the demo synchronously; a read error is yielded once as the
+// last element. Breaking out of the loop stops reading for good: the rest of
+// the current block is discarded and later reads fail with ErrStopped.
+func (self *DemoReader) Events() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		err := self.blockLoop(context.Background(), func(entry Event) error {
+			if !yield(entry, nil) {
+				return ErrStop
+			}
+			return nil
+		})
+		if err == ErrStop {
+			return
+		}
+		self.err = err
+		if err != nil {
+			yield(nil, err)
 		}
 	}
 }
 
-func (self *DemoReader) Iterate() chan interface{} {
-	result := make(chan interface{})
-	go self.BlockLoop(result)
+func (self *DemoReader) IterateContext(ctx context.Context) chan Event {
+	result := make(chan Event)
+	go self.blockLoopContext(ctx, result)
 	return result
 }
@@ -1,7 +1,9 @@
-package main
+package qldemo
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -25,7 +27,244 @@ func TestDuelDemo(tst *testing.T) {
 		default:
 		}
 	}
+	if reader.Err() != nil {
+		tst.Errorf("Unexpected error: %v", reader.Err())
+	}
 	if !gg {
 		tst.Errorf("Expected a gg message but did not find it.")
 	}
 }
+
+func TestTruncatedDemo(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw[:len(raw)/2]), NewDemoState())
+
+	for range reader.Iterate() {
+	}
+	if reader.Err() != ErrTruncated {
+		tst.Errorf("Expected ErrTruncated but got %v.", reader.Err())
+	}
+}
+
+func TestUnknownOpcode(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	corrupt := append([]byte{}, raw...)
+	for i := 12; i < 64; i += 1 {
+		corrupt[i] = 0xff
+	}
+	reader := NewDemoReader(bytes.NewReader(corrupt), NewDemoState())
+
+	for range reader.Iterate() {
+	}
+	if _, ok := reader.Err().(*ErrUnknownOpcode); !ok {
+		tst.Errorf("Expected ErrUnknownOpcode but got %v.", reader.Err())
+	}
+}
+
+// huffmanBits returns the bits that BitDecoder decodes as value.
+func huffmanBits(value int) []int {
+	for index := range decoderTree {
+		if int(decoderTree[index]) != value {
+			continue
+		}
+		bits := []int{}
+		for node := index; node > 1; node /= 2 {
+			bits = append([]int{node & 1}, bits...)
+		}
+		return bits
+	}
+	return nil
+}
+
+func packBits(bits []int) []byte {
+	result := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		result[i/8] |= byte(bit << uint(i%8))
+	}
+	return result
+}
+
+func TestBadFieldCount(tst *testing.T) {
+	bits := append([]int{1}, huffmanBits(60)...)
+	reader := NewStateReader(NewDataReader(NewBitReader(bytes.NewReader(packBits(bits)))))
+	err := reader.ReadEntity(new(Entity))
+	if bad, ok := err.(*ErrBadFieldCount); !ok || bad.Count != 60 {
+		tst.Errorf("Expected ErrBadFieldCount for 60 entity fields but got %v.", err)
+	}
+
+	reader = NewStateReader(NewDataReader(NewBitReader(bytes.NewReader(packBits(huffmanBits(60))))))
+	if _, ok := reader.ReadPlayer(new(Player)).(*ErrBadFieldCount); !ok {
+		tst.Errorf("Expected ErrBadFieldCount for 60 player fields.")
+	}
+}
+
+func TestBadBlockLength(tst *testing.T) {
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint32(raw[4:], 1<<31-1)
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	for range reader.Iterate() {
+	}
+	if _, ok := reader.Err().(*ErrBadBlockLength); !ok {
+		tst.Errorf("Expected ErrBadBlockLength but got %v.", reader.Err())
+	}
+}
+
+func TestCancelledIteration(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	channel := reader.IterateContext(ctx)
+	<-channel
+	cancel()
+	for range channel {
+	}
+	if reader.Err() != context.Canceled {
+		tst.Errorf("Expected context.Canceled but got %v.", reader.Err())
+	}
+}
+
+func TestEvents(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	count := 0
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		if entry.Kind() == KIND_SNAPSHOT {
+			count += 1
+		}
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		tst.Errorf("Expected to stop after 10 snapshots but got %v.", count)
+	}
+	var stopped error
+	for _, err := range reader.Events() {
+		stopped = err
+	}
+	if stopped != ErrStopped {
+		tst.Errorf("Expected ErrStopped after breaking but got %v.", stopped)
+	}
+
+	reader = NewDemoReader(bytes.NewReader(raw[:len(raw)/2]), NewDemoState())
+	var last error
+	for _, err := range reader.Events() {
+		last = err
+	}
+	if last != ErrTruncated {
+		tst.Errorf("Expected ErrTruncated but got %v.", last)
+	}
+}
+
+// gapped returns the demo with blocks 100 to 149 removed, so that the
+// snapshots after the gap are delta compressed against missing frames.
+func gapped(raw []byte) []byte {
+	offsets := []int{}
+	for offset := 0; offset+8 <= len(raw); {
+		offsets = append(offsets, offset)
+		length := int(int32(binary.LittleEndian.Uint32(raw[offset+4:])))
+		if length < 0 {
+			break
+		}
+		offset += 8 + length
+	}
+	return append(append([]byte{}, raw[:offsets[100]]...), raw[offsets[150]:]...)
+}
+
+func TestDroppedSnapshots(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(gapped(raw)), NewDemoState())
+
+	dropped, snapshots := 0, 0
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		switch entry := entry.(type) {
+		case *SnapshotDropped:
+			dropped += 1
+			if entry.Err != ErrDeltaInvalid && entry.Err != ErrDeltaTooOld {
+				tst.Errorf("Unexpected delta error: %v", entry.Err)
+			}
+		case *Snapshot:
+			snapshots += 1
+		}
+	}
+	if dropped == 0 || snapshots == 0 {
+		tst.Errorf("Expected dropped and decoded snapshots but got %v and %v.", dropped, snapshots)
+	}
+}
+
+func TestSnapshotState(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	snapshots := []*Snapshot{}
+	for entry := range reader.Iterate() {
+		if snapshot, ok := entry.(*Snapshot); ok && len(snapshots) < 100 {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ServerTime != snapshot.Time {
+			tst.Errorf("Snapshot server time does not match its event info.")
+		}
+		if snapshot.Player.CommandTime > snapshot.Time {
+			tst.Errorf("Player command time %v is ahead of snapshot %v.", snapshot.Player.CommandTime, snapshot.Time)
+		}
+		if len(snapshot.Entities) == 0 {
+			tst.Errorf("Expected entities in snapshot %v.", snapshot.Time)
+		}
+	}
+	if snapshots[0].Player == snapshots[len(snapshots)-1].Player {
+		tst.Errorf("Expected snapshots to carry their own player state.")
+	}
+}
+
+func TestMessageCommand(tst *testing.T) {
+	demoState := NewDemoState()
+	commands := []string{`bcs0 529 "n\Ven"`, `bcs1 529 "tz\t\0"`, `bcs2 529 "\c\au"`, `cs 5 "\time\0"`}
+	var change *ConfigStringChanged
+	for i, command := range commands {
+		var err error
+		if change, err = demoState.OnMessageCommand(i+1, command); err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		if i == 2 && (change == nil || change.Index != 529 || change.New != `n\Ventz\t\0\c\au`) {
+			tst.Errorf("Unexpected big config string change %+v.", change)
+		}
+	}
+	if change == nil || change.Index != CS_WARMUP || demoState.Config[CS_WARMUP] != `\time\0` {
+		tst.Errorf("Unexpected config string change %+v.", change)
+	}
+	if change, _ := demoState.OnMessageCommand(4, `cs 5 "\time\1"`); change != nil {
+		tst.Errorf("Expected a retransmitted command to be ignored.")
+	}
+	if _, err := demoState.OnMessageCommand(5, `bcs1 6 "x"`); err == nil {
+		tst.Errorf("Expected an error for a big config string without a start.")
+	}
+}
@@ -0,0 +1,141 @@
+package qldemo
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Team int
+
+const (
+	TEAM_FREE Team = iota
+	TEAM_RED
+	TEAM_BLUE
+	TEAM_SPECTATOR
+)
+
+var teamNames = map[Team]string{
+	TEAM_FREE:      "Free",
+	TEAM_RED:       "Red",
+	TEAM_BLUE:      "Blue",
+	TEAM_SPECTATOR: "Spectator",
+}
+
+func (self Team) String() string {
+	if name, ok := teamNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("Team(%d)", int(self))
+}
+
+// PlayerInfo is the decoded CS_PLAYERS config string of a client slot.
+type PlayerInfo struct {
+	Info
+	Client    int
+	Name      ColorString
+	CleanName string
+	Clan      ColorString
+	ClanName  string
+	Team      Team
+	Model     string
+	HeadModel string
+	Handicap  int
+	Skill     float64
+	SteamId   string
+	Country   string
+}
+
+func ParsePlayerInfo(client int, str string) *PlayerInfo {
+	info := ParseInfo(str)
+	skill, _ := strconv.ParseFloat(info["skill"], 64)
+	return &PlayerInfo{
+		Info:      info,
+		Client:    client,
+		Name:      ColorString(info["n"]),
+		CleanName: ColorString(info["n"]).Clean(),
+		Clan:      ColorString(info["cn"]),
+		ClanName:  info["xcn"],
+		Team:      Team(info.Int("t")),
+		Model:     info["model"],
+		HeadModel: info["hmodel"],
+		Handicap:  info.Int("hc"),
+		Skill:     skill,
+		SteamId:   info["st"],
+		Country:   info["c"],
+	}
+}
+
+/* -------------------------------------------- */
+// Roster maps client numbers to the players in CS_PLAYERS.
+type Roster map[int]*PlayerInfo
+
+func (self Roster) Team(team Team) []*PlayerInfo {
+	result := []*PlayerInfo{}
+	for client := 0; client < MAX_CLIENTS; client += 1 {
+		if player, ok := self[client]; ok && player.Team == team {
+			result = append(result, player)
+		}
+	}
+	return result
+}
+
+func (self Roster) Name(client int) ColorString {
+	if player, ok := self[client]; ok {
+		return player.Name
+	}
+	return ""
+}
+
+type PlayerConnected struct {
+	EventInfo
+	Player *PlayerInfo
+}
+
+func (self *PlayerConnected) Kind() EventKind {
+	return KIND_PLAYER_CONNECTED
+}
+
+type PlayerDisconnected struct {
+	EventInfo
+	Player *PlayerInfo
+}
+
+func (self *PlayerDisconnected) Kind() EventKind {
+	return KIND_PLAYER_DISCONNECTED
+}
+
+type PlayerTeamChanged struct {
+	EventInfo
+	Player  *PlayerInfo
+	OldTeam Team
+}
+
+func (self *PlayerTeamChanged) Kind() EventKind {
+	return KIND_PLAYER_TEAM_CHANGED
+}
+
+// updateRoster decodes config string index into the roster if it is a player
+// config string, returning the roster event it caused or nil.
+func (self *DemoState) updateRoster(index int) Event {
+	if index < CS_PLAYERS || index >= CS_PLAYERS+MAX_CLIENTS {
+		return nil
+	}
+	client := index - CS_PLAYERS
+	old := self.Roster[client]
+	if self.Config[index] == "" {
+		delete(self.Roster, client)
+		if old != nil {
+			return &PlayerDisconnected{Player: old}
+		}
+		return nil
+	}
+
+	player := ParsePlayerInfo(client, self.Config[index])
+	self.Roster[client] = player
+	if old == nil {
+		return &PlayerConnected{Player: player}
+	} else if old.Team != player.Team {
+		return &PlayerTeamChanged{Player: player, OldTeam: old.Team}
+	}
+	return nil
+}
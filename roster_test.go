@@ -0,0 +1,56 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDuelRoster(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	demoState := NewDemoState()
+	reader := NewDemoReader(bytes.NewReader(raw), demoState)
+
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		if entry.Kind() == KIND_GAMESTATE {
+			break
+		}
+	}
+	players := demoState.Roster.Team(TEAM_FREE)
+	if len(players) != 2 || players[0].CleanName != "Ventz" || players[1].Name != "ZenAku" {
+		tst.Errorf("Unexpected players %+v.", players)
+	}
+	if len(demoState.Roster.Team(TEAM_SPECTATOR)) != 13 || demoState.Roster[3].ClanName != "Sequential Gaming" {
+		tst.Errorf("Unexpected roster %+v.", demoState.Roster)
+	}
+}
+
+func TestRosterEvents(tst *testing.T) {
+	demoState := NewDemoState()
+	demoState.OnBaselineConfig(CS_PLAYERS+1, `n\^1Foo\t\3`)
+
+	expected := []EventKind{KIND_PLAYER_TEAM_CHANGED, KIND_NONE, KIND_PLAYER_DISCONNECTED, KIND_PLAYER_CONNECTED}
+	commands := []string{`cs 530 "n\^1Foo\t\1"`, `cs 530 "n\^2Foo\t\1"`, `cs 530 ""`, `cs 530 "n\Bar\t\2"`}
+	for i, command := range commands {
+		change, err := demoState.OnMessageCommand(i+1, command)
+		if err != nil || change == nil {
+			tst.Fatalf("Unexpected result %v, %v.", change, err)
+		}
+		kind := KIND_NONE
+		if entry := demoState.updateRoster(change.Index); entry != nil {
+			kind = entry.Kind()
+		}
+		if kind != expected[i] {
+			tst.Errorf("Expected %v but got %v for %v.", expected[i], kind, command)
+		}
+	}
+	if player := demoState.Roster[1]; player == nil || player.Name != "Bar" || player.Team != TEAM_BLUE {
+		tst.Errorf("Unexpected player %+v.", player)
+	}
+}
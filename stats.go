@@ -0,0 +1,118 @@
+package qldemo
+
+// PlayerStats are the statistics of a client slot over a match. Deaths
+// include suicides and deaths to the world, which are also counted in
+// Suicides and WorldDeaths, and streaks count kills between deaths.
+// KillDeathRatio is Kills while there are no deaths. Pickups counts the items
+// picked up by the client while it was visible.
+type PlayerStats struct {
+	Client         int
+	Name           ColorString
+	Kills          int
+	Deaths         int
+	Suicides       int
+	WorldDeaths    int
+	KillDeathRatio float64
+	KillsByWeapon  map[Weapon]int
+	DeathsByWeapon map[Weapon]int
+	Pickups        map[Item]int
+	Streak         int
+	BestStreak     int
+	TimePlayed     int // milliseconds spent on a playing team
+}
+
+func newPlayerStats(client int) *PlayerStats {
+	return &PlayerStats{Client: client, KillsByWeapon: make(map[Weapon]int),
+		DeathsByWeapon: make(map[Weapon]int), Pickups: make(map[Item]int)}
+}
+
+func (self *PlayerStats) update() {
+	deaths := self.Deaths
+	if deaths == 0 {
+		deaths = 1
+	}
+	self.KillDeathRatio = float64(self.Kills) / float64(deaths)
+}
+
+/* -------------------------------------------- */
+// MatchStats aggregates PlayerStats from the events of a DemoReader as they
+// are read. Warmup does not count towards the match, as it ends with a map
+// restart.
+type MatchStats struct {
+	Time      int
+	Players   map[int]*PlayerStats
+	demoState *DemoState
+}
+
+func NewMatchStats(demoState *DemoState) *MatchStats {
+	return &MatchStats{Players: make(map[int]*PlayerStats), demoState: demoState}
+}
+
+func (self *MatchStats) Reset() {
+	self.Time = 0
+	self.Players = make(map[int]*PlayerStats)
+}
+
+func (self *MatchStats) player(client int) *PlayerStats {
+	stats, ok := self.Players[client]
+	if !ok {
+		stats = newPlayerStats(client)
+		self.Players[client] = stats
+	}
+	if player, ok := self.demoState.Roster[client]; ok {
+		stats.Name = player.Name
+	}
+	return stats
+}
+
+func (self *MatchStats) OnEvent(event Event) error {
+	switch typed := event.(type) {
+	case *Gamestate, *MapRestart:
+		self.Reset()
+	case *Snapshot:
+		self.onSnapshot(typed)
+	case *Obituary:
+		self.onObituary(typed)
+	case *ItemPickup:
+		if typed.Client >= 0 && typed.Client < MAX_CLIENTS {
+			self.player(typed.Client).Pickups[typed.Item] += 1
+		}
+	}
+	return nil
+}
+
+func (self *MatchStats) onSnapshot(snapshot *Snapshot) {
+	elapsed := snapshot.Time - self.Time
+	if self.Time == 0 || elapsed < 0 {
+		elapsed = 0
+	}
+	self.Time = snapshot.Time
+	for client, player := range self.demoState.Roster {
+		if player.Team != TEAM_SPECTATOR {
+			self.player(client).TimePlayed += elapsed
+		}
+	}
+}
+
+func (self *MatchStats) onObituary(obituary *Obituary) {
+	weapon := obituary.MeansOfDeath.Weapon()
+	target := self.player(obituary.Target)
+	target.Deaths += 1
+	target.DeathsByWeapon[weapon] += 1
+	target.Streak = 0
+	if obituary.IsWorldKill() {
+		target.WorldDeaths += 1
+	} else if obituary.IsSuicide() {
+		target.Suicides += 1
+	} else {
+		attacker := self.player(obituary.Attacker)
+		attacker.Kills += 1
+		attacker.KillsByWeapon[weapon] += 1
+		attacker.Streak += 1
+		if attacker.Streak > attacker.BestStreak {
+			attacker.BestStreak = attacker.Streak
+		}
+		attacker.update()
+	}
+	target.update()
+}
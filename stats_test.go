@@ -0,0 +1,53 @@
+package qldemo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDuelMatchStats(tst *testing.T) {
+	demoState := NewDemoState()
+	stats := NewMatchStats(demoState)
+	replayDuel(tst, demoState, stats)
+
+	ventz, zenaku := stats.Players[0], stats.Players[3]
+	if len(stats.Players) != 2 || ventz.Kills != 6 || zenaku.Kills != 9 ||
+		ventz.Deaths != zenaku.Kills+ventz.Suicides+ventz.WorldDeaths ||
+		zenaku.Deaths != ventz.Kills+zenaku.Suicides+zenaku.WorldDeaths {
+		tst.Errorf("Unexpected stats %+v and %+v.", ventz, zenaku)
+	}
+	if zenaku.Name.Clean() != "ZenAku" || zenaku.KillDeathRatio != float64(zenaku.Kills)/float64(zenaku.Deaths) {
+		tst.Errorf("Unexpected stats %+v.", zenaku)
+	}
+	if zenaku.Pickups[Item(8)] == 0 || ventz.Pickups[Item(8)] == 0 {
+		tst.Errorf("Expected both players to pick up mega health but got %v and %v.", ventz.Pickups, zenaku.Pickups)
+	}
+	if zenaku.BestStreak < 1 || zenaku.TimePlayed < 9*60*1000 || zenaku.TimePlayed > 11*60*1000 {
+		tst.Errorf("Unexpected streak %v or time played %v.", zenaku.BestStreak, zenaku.TimePlayed)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		tst.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := &MatchStats{}
+	if err := json.Unmarshal(data, decoded); err != nil || !reflect.DeepEqual(decoded.Players, stats.Players) {
+		tst.Errorf("Expected the stats to survive serialisation: %v", err)
+	}
+}
+
+func TestWorldDeaths(tst *testing.T) {
+	stats := NewMatchStats(NewDemoState())
+	stats.OnEvent(&Obituary{Attacker: ENTITYNUM_WORLD, Target: 1, MeansOfDeath: MOD_FALLING})
+	stats.OnEvent(&Obituary{Attacker: 1, Target: 1, MeansOfDeath: MOD_ROCKET_SPLASH})
+	stats.OnEvent(&Obituary{Attacker: 2, Target: 1, MeansOfDeath: MOD_RAILGUN})
+
+	player := stats.Players[1]
+	if stats.Players[2].KillDeathRatio != 1 {
+		tst.Errorf("Expected the ratio of a player without deaths to be their kills.")
+	}
+	if player.Deaths != 3 || player.WorldDeaths != 1 || player.Suicides != 1 || stats.Players[2].Kills != 1 {
+		tst.Errorf("Unexpected stats %+v.", player)
+	}
+}
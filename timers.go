@@ -0,0 +1,185 @@
+package qldemo
+
+import "math"
+
+// Respawn times in milliseconds, as seen in the bundled duel; holdables and
+// powerups keep the Quake III defaults.
+const (
+	RESPAWN_ARMOR      = 25000
+	RESPAWN_HEALTH     = 35000
+	RESPAWN_MEGAHEALTH = 120000
+	RESPAWN_AMMO       = 40000
+	RESPAWN_WEAPON     = 5000
+	RESPAWN_RAILGUN    = 15000
+	RESPAWN_HOLDABLE   = 60000
+	RESPAWN_POWERUP    = 120000
+
+	// farther pickups are of dropped items rather than map items
+	MAX_PICKUP_DISTANCE = 96
+)
+
+// RespawnTime returns the default time item takes to respawn after it is
+// picked up, or 0 for items that do not respawn.
+func RespawnTime(item Item) int {
+	switch item.Info().ClassName {
+	case "item_health_mega":
+		return RESPAWN_MEGAHEALTH
+	case "weapon_railgun":
+		return RESPAWN_RAILGUN
+	}
+	switch item.Info().Type {
+	case IT_ARMOR:
+		return RESPAWN_ARMOR
+	case IT_HEALTH:
+		return RESPAWN_HEALTH
+	case IT_AMMO:
+		return RESPAWN_AMMO
+	case IT_WEAPON:
+		return RESPAWN_WEAPON
+	case IT_HOLDABLE:
+		return RESPAWN_HOLDABLE
+	case IT_POWERUP, IT_PERSISTANT_POWERUP:
+		return RESPAWN_POWERUP
+	}
+	return 0
+}
+
+/* -------------------------------------------- */
+// ItemDowntime is a period an item was taken. Respawn is the observed
+// EV_ITEM_RESPAWN time if Observed and otherwise when it is expected. A
+// respawn observed without a pickup is recorded with Client -1 and Taken
+// estimated from the respawn time.
+type ItemDowntime struct {
+	Taken    int
+	Client   int
+	Respawn  int
+	Observed bool
+}
+
+// ItemTimeline is the availability of a map item. RespawnTime starts at the
+// default of the item and is lowered to the shortest observed respawn, as
+// longer ones may follow pickups that were not seen.
+type ItemTimeline struct {
+	MapItem
+	RespawnTime int
+	Downtimes   []ItemDowntime
+}
+
+func (self *ItemTimeline) Available(time int) bool {
+	for _, downtime := range self.Downtimes {
+		if downtime.Taken <= time && time < downtime.Respawn {
+			return false
+		}
+	}
+	return true
+}
+
+// Control returns the number of times each client took the item.
+func (self *ItemTimeline) Control() map[int]int {
+	result := map[int]int{}
+	for _, downtime := range self.Downtimes {
+		if downtime.Client >= 0 {
+			result[downtime.Client] += 1
+		}
+	}
+	return result
+}
+
+func (self *ItemTimeline) last() *ItemDowntime {
+	if len(self.Downtimes) == 0 {
+		return nil
+	}
+	return &self.Downtimes[len(self.Downtimes)-1]
+}
+
+/* -------------------------------------------- */
+// ItemTimers reconstructs the ItemTimeline of every map item from pickups and
+// respawn events. Pickups are matched to the nearest map item of their kind
+// within MAX_PICKUP_DISTANCE, preferring those that are available. All items
+// are available again after a map restart.
+type ItemTimers struct {
+	Items     []*ItemTimeline
+	demoState *DemoState
+}
+
+func NewItemTimers(demoState *DemoState) *ItemTimers {
+	return &ItemTimers{Items: []*ItemTimeline{}, demoState: demoState}
+}
+
+func (self *ItemTimers) Reset() {
+	self.Items = []*ItemTimeline{}
+	for _, item := range self.demoState.MapItems() {
+		self.Items = append(self.Items, &ItemTimeline{MapItem: item, RespawnTime: RespawnTime(item.Item),
+			Downtimes: []ItemDowntime{}})
+	}
+}
+
+// Item returns the timeline of the map item with entity number entity.
+func (self *ItemTimers) Item(entity int) *ItemTimeline {
+	for _, timeline := range self.Items {
+		if timeline.Entity == entity {
+			return timeline
+		}
+	}
+	return nil
+}
+
+func (self *ItemTimers) OnEvent(event Event) error {
+	switch typed := event.(type) {
+	case *Gamestate, *MapRestart:
+		self.Reset()
+	case *ItemPickup:
+		self.onPickup(typed)
+	case *GameEvent:
+		if typed.Event == EV_ITEM_RESPAWN {
+			self.onRespawn(typed.Entity, typed.ServerTime)
+		}
+	}
+	return nil
+}
+
+func (self *ItemTimers) onPickup(pickup *ItemPickup) {
+	var best *ItemTimeline
+	bestAvailable, bestDistance := false, math.MaxFloat64
+	for _, timeline := range self.Items {
+		distance := distance(timeline.Origin, pickup.Origin)
+		if timeline.Item != pickup.Item || distance > MAX_PICKUP_DISTANCE {
+			continue
+		}
+		available := timeline.Available(pickup.Time)
+		if best == nil || (available && !bestAvailable) ||
+			(available == bestAvailable && distance < bestDistance) {
+			best, bestAvailable, bestDistance = timeline, available, distance
+		}
+	}
+	if best == nil {
+		return
+	}
+	if last := best.last(); last != nil && last.Respawn > pickup.Time {
+		last.Respawn = pickup.Time
+	}
+	best.Downtimes = append(best.Downtimes, ItemDowntime{Taken: pickup.Time, Client: pickup.Client,
+		Respawn: pickup.Time + best.RespawnTime})
+}
+
+func (self *ItemTimers) onRespawn(entity, time int) {
+	timeline := self.Item(entity)
+	if timeline == nil {
+		return
+	}
+	last := timeline.last()
+	if last == nil || last.Observed {
+		timeline.Downtimes = append(timeline.Downtimes, ItemDowntime{Taken: time - timeline.RespawnTime,
+			Client: -1, Respawn: time, Observed: true})
+		return
+	}
+	last.Respawn, last.Observed = time, true
+	if respawnTime := time - last.Taken; respawnTime < timeline.RespawnTime {
+		timeline.RespawnTime = respawnTime
+	}
+}
+
+func distance(a, b Vector) float64 {
+	x, y, z := float64(a.X-b.X), float64(a.Y-b.Y), float64(a.Z-b.Z)
+	return math.Sqrt(x*x + y*y + z*z)
+}
@@ -0,0 +1,50 @@
+package qldemo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDuelItemTimers(tst *testing.T) {
+	demoState := NewDemoState()
+	timers := NewItemTimers(demoState)
+	replayDuel(tst, demoState, timers)
+	if len(timers.Items) != 29 {
+		tst.Errorf("Expected a timeline per map item but got %v.", len(timers.Items))
+	}
+	mega := timers.Item(95)
+	if mega == nil || mega.Item.String() != "Mega Health" || !reflect.DeepEqual(mega.Control(), map[int]int{0: 2, 3: 3}) {
+		tst.Fatalf("Unexpected mega health timeline %+v.", mega)
+	}
+	if mega.RespawnTime < RESPAWN_MEGAHEALTH-100 || mega.RespawnTime > RESPAWN_MEGAHEALTH {
+		tst.Errorf("Unexpected mega health respawn time %v.", mega.RespawnTime)
+	}
+	if shells := timers.Item(190); shells == nil || shells.RespawnTime != RESPAWN_AMMO {
+		tst.Errorf("Expected shells only respawning late to keep the default but got %+v.", shells)
+	}
+	if rail := timers.Item(188); rail == nil || rail.RespawnTime < RESPAWN_RAILGUN-500 || rail.RespawnTime > RESPAWN_RAILGUN {
+		tst.Errorf("Unexpected railgun timeline %+v.", rail)
+	}
+	first := mega.Downtimes[0]
+	if !first.Observed || mega.Available(first.Taken) || !mega.Available(first.Respawn) || !mega.Available(first.Taken-1) {
+		tst.Errorf("Unexpected mega health availability around %+v.", first)
+	}
+}
+
+func TestLateRespawn(tst *testing.T) {
+	demoState := NewDemoState()
+	demoState.OnBaselineEntity(100, &Entity{EType: int(ET_ITEM), ModelIndex: 19})
+	timers := NewItemTimers(demoState)
+	timers.Reset()
+
+	timers.OnEvent(&ItemPickup{Time: 1000, Client: 0, Item: 19})
+	timers.OnEvent(&GameEvent{EventInfo: EventInfo{ServerTime: 91500}, Event: EV_ITEM_RESPAWN, Entity: 100})
+	if shells := timers.Item(100); shells.RespawnTime != RESPAWN_AMMO {
+		tst.Errorf("Expected a late respawn to keep the default but got %v.", shells.RespawnTime)
+	}
+	timers.OnEvent(&ItemPickup{Time: 100000, Client: 0, Item: 19})
+	timers.OnEvent(&GameEvent{EventInfo: EventInfo{ServerTime: 139975}, Event: EV_ITEM_RESPAWN, Entity: 100})
+	if shells := timers.Item(100); shells.RespawnTime != 39975 {
+		tst.Errorf("Expected a shorter respawn to be learned but got %v.", shells.RespawnTime)
+	}
+}
@@ -0,0 +1,98 @@
+package qldemo
+
+import (
+	"fmt"
+	"math"
+)
+
+const DEFAULT_GRAVITY = 800
+
+type TrajectoryType int
+
+const (
+	TR_STATIONARY TrajectoryType = iota
+	TR_INTERPOLATE
+	TR_LINEAR
+	TR_LINEAR_STOP
+	TR_SINE
+	TR_GRAVITY
+)
+
+var trajectoryTypeNames = map[TrajectoryType]string{
+	TR_STATIONARY:  "stationary",
+	TR_INTERPOLATE: "interpolate",
+	TR_LINEAR:      "linear",
+	TR_LINEAR_STOP: "linear stop",
+	TR_SINE:        "sine",
+	TR_GRAVITY:     "gravity",
+}
+
+func (self TrajectoryType) String() string {
+	if name, ok := trajectoryTypeNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("TrajectoryType(%d)", int(self))
+}
+
+/* -------------------------------------------- */
+func (self Vector) add(other Vector, scale float32) Vector {
+	return Vector{self.X + other.X*scale, self.Y + other.Y*scale, self.Z + other.Z*scale}
+}
+
+// gravity returns the gravity of a TR_GRAVITY trajectory, which protocol 73
+// sends only when it is not DEFAULT_GRAVITY.
+func (self *Trajectory) gravity() float32 {
+	if self.Gravity == 0 {
+		return DEFAULT_GRAVITY
+	}
+	return float32(self.Gravity)
+}
+
+// PositionAt evaluates the trajectory at server time, as BG_EvaluateTrajectory
+// does. Unknown types are treated as stationary.
+func (self *Trajectory) PositionAt(time int) Vector {
+	switch TrajectoryType(self.Type) {
+	case TR_LINEAR:
+		return self.Base.add(self.Delta, float32(time-self.Time)*0.001)
+	case TR_LINEAR_STOP:
+		if time > self.Time+self.Duration {
+			time = self.Time + self.Duration
+		}
+		elapsed := float32(time-self.Time) * 0.001
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		return self.Base.add(self.Delta, elapsed)
+	case TR_SINE:
+		phase := math.Sin(float64(time-self.Time) / float64(self.Duration) * math.Pi * 2)
+		return self.Base.add(self.Delta, float32(phase))
+	case TR_GRAVITY:
+		elapsed := float32(time-self.Time) * 0.001
+		result := self.Base.add(self.Delta, elapsed)
+		result.Z -= 0.5 * self.gravity() * elapsed * elapsed
+		return result
+	}
+	return self.Base
+}
+
+// VelocityAt evaluates the derivative of the trajectory at server time, as
+// BG_EvaluateTrajectoryDelta does, including its halved TR_SINE velocity.
+func (self *Trajectory) VelocityAt(time int) Vector {
+	switch TrajectoryType(self.Type) {
+	case TR_LINEAR:
+		return self.Delta
+	case TR_LINEAR_STOP:
+		if time > self.Time+self.Duration {
+			return Vector{}
+		}
+		return self.Delta
+	case TR_SINE:
+		phase := math.Cos(float64(time-self.Time) / float64(self.Duration) * math.Pi * 2)
+		return Vector{}.add(self.Delta, float32(phase*0.5))
+	case TR_GRAVITY:
+		result := self.Delta
+		result.Z -= self.gravity() * float32(time-self.Time) * 0.001
+		return result
+	}
+	return Vector{}
+}
@@ -0,0 +1,90 @@
+package qldemo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func near(a, b Vector) bool {
+	return distance(a, b) < 0.01
+}
+
+func TestTrajectory(tst *testing.T) {
+	base, delta := Vector{100, 200, 300}, Vector{10, -20, 40}
+	cases := []struct {
+		trajectory Trajectory
+		time       int
+		position   Vector
+		velocity   Vector
+	}{
+		{Trajectory{Type: int(TR_STATIONARY), Time: 1000, Base: base, Delta: delta}, 2000, base, Vector{}},
+		{Trajectory{Type: int(TR_INTERPOLATE), Time: 1000, Base: base, Delta: delta}, 2000, base, Vector{}},
+		{Trajectory{Type: int(TR_LINEAR), Time: 1000, Base: base, Delta: delta}, 1500, Vector{105, 190, 320}, delta},
+		{Trajectory{Type: int(TR_LINEAR_STOP), Time: 1000, Duration: 500, Base: base, Delta: delta}, 1250,
+			Vector{102.5, 195, 310}, delta},
+		{Trajectory{Type: int(TR_LINEAR_STOP), Time: 1000, Duration: 500, Base: base, Delta: delta}, 3000,
+			Vector{105, 190, 320}, Vector{}},
+		{Trajectory{Type: int(TR_LINEAR_STOP), Time: 1000, Duration: 500, Base: base, Delta: delta}, 500, base, delta},
+		{Trajectory{Type: int(TR_SINE), Time: 1000, Duration: 2000, Base: base, Delta: delta}, 1500,
+			Vector{110, 180, 340}, Vector{}},
+		{Trajectory{Type: int(TR_SINE), Time: 1000, Duration: 2000, Base: base, Delta: delta}, 2000,
+			base, Vector{-5, 10, -20}},
+		{Trajectory{Type: int(TR_GRAVITY), Time: 1000, Base: base, Delta: delta}, 1500,
+			Vector{105, 190, 220}, Vector{10, -20, -360}},
+		{Trajectory{Type: int(TR_GRAVITY), Time: 1000, Base: base, Delta: delta, Gravity: 400}, 1500,
+			Vector{105, 190, 270}, Vector{10, -20, -160}},
+	}
+	for _, c := range cases {
+		kind := TrajectoryType(c.trajectory.Type)
+		if position := c.trajectory.PositionAt(c.time); !near(position, c.position) {
+			tst.Errorf("Expected %v position %v at %v but got %v.", kind, c.position, c.time, position)
+		}
+		if velocity := c.trajectory.VelocityAt(c.time); !near(velocity, c.velocity) {
+			tst.Errorf("Expected %v velocity %v at %v but got %v.", kind, c.velocity, c.time, velocity)
+		}
+	}
+	if TR_LINEAR_STOP.String() != "linear stop" || TrajectoryType(9).String() != "TrajectoryType(9)" {
+		tst.Errorf("Unexpected trajectory type names.")
+	}
+}
+
+func TestDuelMissileTrajectories(tst *testing.T) {
+	raw, err := ioutil.ReadFile("duel.dm_73")
+	if err != nil {
+		tst.Errorf("Failed to load duel.dm_73")
+	}
+	reader := NewDemoReader(bytes.NewReader(raw), NewDemoState())
+
+	missiles := 0
+	for entry, err := range reader.Events() {
+		if err != nil {
+			tst.Fatalf("Unexpected error: %v", err)
+		}
+		snapshot, ok := entry.(*Snapshot)
+		if !ok {
+			continue
+		}
+		for id, missile := range snapshot.Missiles() {
+			trajectory := &missile.Pos
+			if kind := TrajectoryType(trajectory.Type); kind != TR_LINEAR && kind != TR_GRAVITY {
+				continue
+			}
+			missiles += 1
+			if !near(trajectory.PositionAt(trajectory.Time), trajectory.Base) {
+				tst.Fatalf("Expected missile %v to start at its base.", id)
+			}
+			if kind := TrajectoryType(trajectory.Type); kind == TR_LINEAR {
+				speed := distance(trajectory.VelocityAt(snapshot.Time), Vector{})
+				travelled := distance(trajectory.PositionAt(snapshot.Time), trajectory.Base)
+				if expected := speed * float64(snapshot.Time-trajectory.Time) * 0.001; math.Abs(travelled-expected) > 0.5 {
+					tst.Fatalf("Expected missile %v to travel %v units but got %v.", id, expected, travelled)
+				}
+			}
+		}
+	}
+	if missiles == 0 {
+		tst.Errorf("Expected missiles in the demo.")
+	}
+}